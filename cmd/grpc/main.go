@@ -18,9 +18,12 @@ func main() {
 
 	application := app.New(configData)
 	go application.GrpcSrv.MustRun()
+	if application.HttpSrv != nil {
+		go application.HttpSrv.MustRun()
+	}
 	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	sign := <-stop
 	log.Println("Stopping by signal ", sign)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-	application.GrpcSrv.Stop()
+	application.Stop()
 }
@@ -2,16 +2,30 @@ package app
 
 import (
 	grpcapp "auth-service/internal/app/grpc"
+	httpapp "auth-service/internal/app/http"
 	"auth-service/internal/config"
+	"auth-service/internal/http/introspection"
 	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/keywrap"
+	"auth-service/internal/lib/passhash"
+	"auth-service/internal/lib/pepper"
+	"auth-service/internal/lib/pwned"
 	"auth-service/internal/services/auth"
+	"auth-service/internal/services/keys"
 	"auth-service/internal/storage/kafka"
 	"auth-service/internal/storage/postgres"
 	"auth-service/internal/storage/redis"
+	"context"
+	"log"
+	"time"
 )
 
 type App struct {
 	GrpcSrv *grpcapp.App
+	// HttpSrv serves token introspection over HTTP, nil unless a port is configured
+	HttpSrv *httpapp.App
+	keys    *keys.Keys
+	auth    *auth.Auth
 }
 
 func New(
@@ -22,13 +36,87 @@ func New(
 		panic(err)
 	}
 
-	jwtService := jwt.NewJwtService([]byte(config.JwtSecret), config.TokenExpireHours)
+	var staticKeys []*jwt.SigningKey
+	if config.JwtSigningAlgorithm != jwt.AlgorithmHS256 {
+		signingKey, err := jwt.LoadSigningKey(config.JwtSigningAlgorithm, config.JwtPrivateKeyPath)
+		if err != nil {
+			panic(err)
+		}
+
+		// The configured key is active until a key from the keyring is promoted
+		signingKey.ActivatesAt = time.Unix(0, 0)
+		staticKeys = append(staticKeys, signingKey)
+	}
+
+	jwtService := jwt.NewJwtService([]byte(config.JwtSecret), config.JwtIssuer, config.AccessTokenExpireMinutes, staticKeys...)
+	// A nil interface, not a nil wrapper, leaves the keyring read-only
+	var keyWrapper keys.KeyWrapper
+	if config.SigningKeyEncryptionKey != nil {
+		wrapper, err := keywrap.New(config.SigningKeyEncryptionKey)
+		if err != nil {
+			panic(err)
+		}
+
+		keyWrapper = wrapper
+	}
+
+	keysService := keys.New(storage, jwtService, keyWrapper, config.AccessTokenExpireMinutes, config.KeyringRefreshInterval)
+	if err = keysService.Reload(context.Background()); err != nil {
+		log.Printf("failed to load signing keys: %v", err)
+	}
+	keysService.StartRefresh()
+
+	hasher, err := passhash.New(config.PasswordHashAlgorithm, passhash.Argon2Params{
+		Memory:      config.Argon2Memory,
+		Iterations:  config.Argon2Iterations,
+		Parallelism: config.Argon2Parallelism,
+	}, config.BcryptCost)
+	if err != nil {
+		panic(err)
+	}
+
+	peppers, err := pepper.New(config.PasswordPeppers, config.PasswordPepperVersion)
+	if err != nil {
+		panic(err)
+	}
+
+	// A nil interface, not a nil index, disables the check
+	var breaches auth.BreachedPasswords
+	if config.PwnedPasswordsPath != "" {
+		index, err := pwned.Open(config.PwnedPasswordsPath, config.PwnedPasswordsMinCount)
+		if err != nil {
+			panic(err)
+		}
+
+		log.Printf("checking passwords against breached password hashes in %s", config.PwnedPasswordsPath)
+		breaches = index
+	}
+
 	redisClient := redis.NewRedis(config)
 	kafkaClient := kafka.New(config.KafkaBrokers)
-	authService := auth.New(storage, storage, jwtService, config, redisClient, kafkaClient)
+	authService := auth.New(storage, storage, jwtService, hasher, peppers, breaches, config, redisClient, kafkaClient)
+	authService.StartPurge()
+
+	grpcApp := grpcapp.New(authService, keysService, config.AdminApiToken, config.ServiceApiToken, config.TrustedProxies, config.GrpcPort)
+
+	var httpApp *httpapp.App
+	if config.IntrospectionHttpPort != 0 {
+		httpApp = httpapp.New(introspection.NewHandler(authService, config.IntrospectionApiToken), config.IntrospectionHttpPort)
+	}
 
-	grpcApp := grpcapp.New(authService, config.GrpcPort)
 	return &App{
 		GrpcSrv: grpcApp,
+		HttpSrv: httpApp,
+		keys:    keysService,
+		auth:    authService,
+	}
+}
+
+func (a *App) Stop() {
+	if a.HttpSrv != nil {
+		a.HttpSrv.Stop()
 	}
+	a.GrpcSrv.Stop()
+	a.keys.Stop()
+	a.auth.Stop()
 }
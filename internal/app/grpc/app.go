@@ -1,10 +1,12 @@
 package grpcapp
 
 import (
+	adminGrpc "auth-service/internal/grpc/admin"
 	authGrpc "auth-service/internal/grpc/auth"
 	"fmt"
 	"log"
 	"net"
+	"net/netip"
 
 	"google.golang.org/grpc"
 )
@@ -14,9 +16,23 @@ type App struct {
 	port       int
 }
 
-func New(authService authGrpc.Auth, port int) *App {
-	grpcServer := grpc.NewServer()
-	authGrpc.Register(grpcServer, authService)
+type AuthService interface {
+	authGrpc.Auth
+	adminGrpc.Sessions
+	adminGrpc.Accounts
+}
+
+func New(
+	authService AuthService,
+	keysService adminGrpc.Keys,
+	adminToken string,
+	serviceToken string,
+	trustedProxies []netip.Prefix,
+	port int,
+) *App {
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(adminGrpc.UnaryInterceptor(adminToken)))
+	authGrpc.Register(grpcServer, authService, serviceToken, trustedProxies)
+	adminGrpc.Register(grpcServer, keysService, authService, authService)
 	return &App{
 		grpcServer: grpcServer,
 		port:       port,
@@ -0,0 +1,53 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type App struct {
+	httpServer *http.Server
+}
+
+func New(handler http.Handler, port int) *App {
+	return &App{
+		httpServer: &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	log.Println("HTTP server running on", a.httpServer.Addr)
+
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	log.Println("HTTP server shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := a.httpServer.Shutdown(ctx); err != nil {
+		log.Printf("failed to shut down HTTP server: %v", err)
+	}
+}
@@ -1,39 +1,250 @@
 package config
 
 import (
+	"encoding/base64"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	PostgresDsn      string
-	RedisAddress     string
-	RedisPassword    string
-	JwtSecret        string
-	TokenExpireHours time.Duration
-	GrpcPort         int
-	KafkaBrokers     string
+	PostgresDsn              string
+	RedisAddress             string
+	RedisPassword            string
+	JwtSecret                string
+	JwtSigningAlgorithm      string
+	JwtPrivateKeyPath        string
+	SigningKeyEncryptionKey  []byte
+	JwtIssuer                string
+	JwtAudiences             []string
+	PasswordHashAlgorithm    string
+	Argon2Memory             uint32
+	Argon2Iterations         uint32
+	Argon2Parallelism        uint8
+	BcryptCost               int
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordRequiredClasses  []string
+	PasswordMinEntropyBits   int
+	PasswordHistorySize      int
+	PasswordHistoryRetention time.Duration
+	PasswordPeppers          map[int][]byte
+	PasswordPepperVersion    int
+	PwnedPasswordsPath       string
+	PwnedPasswordsMinCount   int
+	LoginThrottleWindow      time.Duration
+	LoginMaxAccountFailures  int
+	LoginMaxIPFailures       int
+	LoginBackoffBase         time.Duration
+	LoginBackoffMax          time.Duration
+	TrustedProxies           []netip.Prefix
+	AccountLockoutThreshold  int
+	AccountLockoutWindow     time.Duration
+	AccountLockoutDuration   time.Duration
+	AccountUnlockTokenTTL    time.Duration
+	AccessTokenExpireMinutes time.Duration
+	RefreshTokenExpireHours  time.Duration
+	KeyringRefreshInterval   time.Duration
+	RevocationFailOpen       bool
+	BlockUnverifiedLogin     bool
+	VerificationTokenTTL     time.Duration
+	PasswordResetTokenTTL    time.Duration
+	EmailRevertTokenTTL      time.Duration
+	AccountDeletionGrace     time.Duration
+	AccountPurgeInterval     time.Duration
+	GrpcPort                 int
+	KafkaBrokers             string
+	AdminApiToken            string
+	IntrospectionHttpPort    int
+	IntrospectionApiToken    string
+	ServiceApiToken          string
 }
 
 func LoadConfig() (*Config, error) {
-	tokenExpireHours, err := strconv.Atoi(os.Getenv("TOKEN_EXPIRE_HOURS"))
-	if err != nil {
-		panic("Could not parse TOKEN_EXPIRE_HOURS")
+	revocationFailMode := getEnvOrDefault("TOKEN_REVOCATION_FAIL_MODE", "closed")
+	if revocationFailMode != "open" && revocationFailMode != "closed" {
+		panic("Could not parse TOKEN_REVOCATION_FAIL_MODE")
 	}
 
-	grpcPort, err := strconv.Atoi(os.Getenv("GRPC_PORT"))
-	if err != nil {
-		panic("Could not parse GRPC_PORT")
+	unverifiedLoginPolicy := getEnvOrDefault("UNVERIFIED_LOGIN_POLICY", "limited")
+	if unverifiedLoginPolicy != "limited" && unverifiedLoginPolicy != "block" {
+		panic("Could not parse UNVERIFIED_LOGIN_POLICY")
+	}
+
+	passwordRequiredClasses := parseList("PASSWORD_REQUIRED_CLASSES")
+	for _, class := range passwordRequiredClasses {
+		if class != "lower" && class != "upper" && class != "digit" && class != "symbol" {
+			panic("Could not parse PASSWORD_REQUIRED_CLASSES")
+		}
+	}
+
+	// Deployments from before refresh tokens only set TOKEN_EXPIRE_HOURS,
+	// which was the lifetime of the access token
+	accessTokenExpireMinutes := 15
+	if os.Getenv("TOKEN_EXPIRE_HOURS") != "" {
+		accessTokenExpireMinutes = mustParseInt("TOKEN_EXPIRE_HOURS") * 60
+	}
+
+	passwordPeppers := parsePeppers("PASSWORD_PEPPERS")
+
+	// New passwords get the newest pepper unless rotation is staged
+	passwordPepperVersion := 0
+	for version := range passwordPeppers {
+		passwordPepperVersion = max(passwordPepperVersion, version)
 	}
 
 	return &Config{
-		PostgresDsn:      os.Getenv("POSTGRES_DSN"),
-		RedisAddress:     os.Getenv("REDIS_ADDRESS"),
-		RedisPassword:    os.Getenv("REDIS_PASSWORD"),
-		JwtSecret:        os.Getenv("JWT_SECRET"),
-		TokenExpireHours: time.Duration(tokenExpireHours) * time.Hour,
-		GrpcPort:         grpcPort,
-		KafkaBrokers:     os.Getenv("KAFKA_BROKERS"),
+		PostgresDsn:              os.Getenv("POSTGRES_DSN"),
+		RedisAddress:             os.Getenv("REDIS_ADDRESS"),
+		RedisPassword:            os.Getenv("REDIS_PASSWORD"),
+		JwtSecret:                os.Getenv("JWT_SECRET"),
+		JwtSigningAlgorithm:      getEnvOrDefault("JWT_SIGNING_ALGORITHM", "HS256"),
+		JwtPrivateKeyPath:        os.Getenv("JWT_PRIVATE_KEY_PATH"),
+		SigningKeyEncryptionKey:  parseBase64("SIGNING_KEY_ENCRYPTION_KEY"),
+		JwtIssuer:                os.Getenv("JWT_ISSUER"),
+		JwtAudiences:             parseList("JWT_AUDIENCES"),
+		PasswordHashAlgorithm:    getEnvOrDefault("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		Argon2Memory:             uint32(parseIntOrDefault("ARGON2_MEMORY_KIB", 19*1024)),
+		Argon2Iterations:         uint32(parseIntOrDefault("ARGON2_ITERATIONS", 2)),
+		Argon2Parallelism:        uint8(parseIntOrDefault("ARGON2_PARALLELISM", 1)),
+		BcryptCost:               parseIntOrDefault("BCRYPT_COST", 10),
+		PasswordMinLength:        parseIntOrDefault("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:        parseIntOrDefault("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequiredClasses:  passwordRequiredClasses,
+		PasswordMinEntropyBits:   parseIntOrDefault("PASSWORD_MIN_ENTROPY_BITS", 30),
+		PasswordHistorySize:      parseIntOrDefault("PASSWORD_HISTORY_SIZE", 5),
+		PasswordHistoryRetention: time.Duration(parseIntOrDefault("PASSWORD_HISTORY_RETENTION_DAYS", 365)) * 24 * time.Hour,
+		PasswordPeppers:          passwordPeppers,
+		PasswordPepperVersion:    parseIntOrDefault("PASSWORD_PEPPER_VERSION", passwordPepperVersion),
+		PwnedPasswordsPath:       os.Getenv("PWNED_PASSWORDS_PATH"),
+		PwnedPasswordsMinCount:   parseIntOrDefault("PWNED_PASSWORDS_MIN_COUNT", 1),
+		LoginThrottleWindow:      time.Duration(parseIntOrDefault("LOGIN_THROTTLE_WINDOW_MINUTES", 15)) * time.Minute,
+		LoginMaxAccountFailures:  parseIntOrDefault("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		LoginMaxIPFailures:       parseIntOrDefault("LOGIN_MAX_IP_FAILURES", 20),
+		LoginBackoffBase:         time.Duration(parseIntOrDefault("LOGIN_BACKOFF_BASE_SECONDS", 1)) * time.Second,
+		LoginBackoffMax:          time.Duration(parseIntOrDefault("LOGIN_BACKOFF_MAX_SECONDS", 15*60)) * time.Second,
+		TrustedProxies:           parsePrefixes("TRUSTED_PROXIES"),
+		AccountLockoutThreshold:  parseIntOrDefault("ACCOUNT_LOCKOUT_THRESHOLD", 10),
+		AccountLockoutWindow:     time.Duration(parseIntOrDefault("ACCOUNT_LOCKOUT_WINDOW_MINUTES", 60)) * time.Minute,
+		AccountLockoutDuration:   time.Duration(parseIntOrDefault("ACCOUNT_LOCKOUT_DURATION_MINUTES", 60)) * time.Minute,
+		AccountUnlockTokenTTL:    time.Duration(parseIntOrDefault("ACCOUNT_UNLOCK_TOKEN_TTL_HOURS", 24)) * time.Hour,
+		AccessTokenExpireMinutes: time.Duration(parseIntOrDefault("ACCESS_TOKEN_EXPIRE_MINUTES", accessTokenExpireMinutes)) * time.Minute,
+		RefreshTokenExpireHours:  time.Duration(parseIntOrDefault("REFRESH_TOKEN_EXPIRE_HOURS", 30*24)) * time.Hour,
+		KeyringRefreshInterval:   time.Duration(parseIntOrDefault("KEYRING_REFRESH_SECONDS", 60)) * time.Second,
+		RevocationFailOpen:       revocationFailMode == "open",
+		BlockUnverifiedLogin:     unverifiedLoginPolicy == "block",
+		VerificationTokenTTL:     time.Duration(parseIntOrDefault("EMAIL_VERIFICATION_TOKEN_TTL_HOURS", 24)) * time.Hour,
+		PasswordResetTokenTTL:    time.Duration(parseIntOrDefault("PASSWORD_RESET_TOKEN_TTL_MINUTES", 30)) * time.Minute,
+		EmailRevertTokenTTL:      time.Duration(parseIntOrDefault("EMAIL_REVERT_TOKEN_TTL_HOURS", 48)) * time.Hour,
+		AccountDeletionGrace:     time.Duration(parseIntOrDefault("ACCOUNT_DELETION_GRACE_DAYS", 30)) * 24 * time.Hour,
+		AccountPurgeInterval:     time.Duration(parseIntOrDefault("ACCOUNT_PURGE_INTERVAL_MINUTES", 60)) * time.Minute,
+		GrpcPort:                 mustParseInt("GRPC_PORT"),
+		KafkaBrokers:             os.Getenv("KAFKA_BROKERS"),
+		AdminApiToken:            os.Getenv("ADMIN_API_TOKEN"),
+		IntrospectionHttpPort:    parseIntOrDefault("INTROSPECTION_HTTP_PORT", 0),
+		IntrospectionApiToken:    os.Getenv("INTROSPECTION_API_TOKEN"),
+		ServiceApiToken:          os.Getenv("SERVICE_API_TOKEN"),
 	}, nil
 }
+
+func mustParseInt(key string) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		panic("Could not parse " + key)
+	}
+
+	return value
+}
+
+func parseIntOrDefault(key string, defaultValue int) int {
+	if os.Getenv(key) == "" {
+		return defaultValue
+	}
+
+	return mustParseInt(key)
+}
+
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+// parseList reads a comma separated list, skipping empty entries
+func parseList(key string) []string {
+	var values []string
+
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// parsePrefixes reads a comma separated list of CIDR ranges. A plain address
+// stands for itself alone.
+func parsePrefixes(key string) []netip.Prefix {
+	var prefixes []netip.Prefix
+
+	for _, entry := range parseList(key) {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			panic("Could not parse " + key)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes
+}
+
+// parsePeppers reads a comma separated list of version:secret pairs
+func parsePeppers(key string) map[int][]byte {
+	peppers := make(map[int][]byte)
+
+	for _, entry := range parseList(key) {
+		version, secret, ok := strings.Cut(entry, ":")
+		if !ok || secret == "" {
+			panic("Could not parse " + key)
+		}
+
+		number, err := strconv.Atoi(version)
+		if err != nil || number <= 0 {
+			panic("Could not parse " + key)
+		}
+
+		if _, ok = peppers[number]; ok {
+			panic("Could not parse " + key)
+		}
+
+		peppers[number] = []byte(secret)
+	}
+
+	return peppers
+}
+
+// parseBase64 reads a base64 encoded secret, nil when it is not set
+func parseBase64(key string) []byte {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		panic("Could not parse " + key)
+	}
+
+	return decoded
+}
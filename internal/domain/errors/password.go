@@ -0,0 +1,35 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrPasswordTooShort              = errors.New("password is too short")
+	ErrPasswordTooLong               = errors.New("password is too long")
+	ErrPasswordMissingCharacterClass = errors.New("password lacks a required kind of character")
+	ErrPasswordTooWeak               = errors.New("password is too easy to guess")
+	ErrPasswordContainsPersonalInfo  = errors.New("password contains the email or username")
+	ErrPasswordBreached              = errors.New("password appears in a known data breach")
+	ErrPasswordReused                = errors.New("password was used recently")
+)
+
+// PasswordPolicyError lists every rule a password breaks. Each violation
+// wraps one of the password errors above, which errors.Is finds through it.
+type PasswordPolicyError struct {
+	Violations []error
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Error())
+	}
+
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
+
+func (e *PasswordPolicyError) Unwrap() []error {
+	return e.Violations
+}
@@ -0,0 +1,9 @@
+package errors
+
+import "errors"
+
+var (
+	ErrSigningKeyNotFound   = errors.New("signing key not found")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyEncryptionMissing = errors.New("signing key encryption key is not configured")
+)
@@ -0,0 +1,23 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrTooManyLoginAttempts = errors.New("too many login attempts")
+
+// LoginThrottledError tells how long the client has to wait before it may
+// try to log in again. errors.Is finds ErrTooManyLoginAttempts through it.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyLoginAttempts, e.RetryAfter)
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
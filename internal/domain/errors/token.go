@@ -0,0 +1,26 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrInvalidAudience     = errors.New("audience is not allowed")
+	// ErrOneTimeTokenNotFound means a one-time token was never issued, already used or expired
+	ErrOneTimeTokenNotFound = errors.New("one-time token not found")
+)
+
+// Reasons an access token is rejected. Each of them also matches ErrInvalidToken.
+var (
+	ErrTokenExpired          = fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	ErrTokenNotYetValid      = fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	ErrTokenSignatureInvalid = fmt.Errorf("%w: token signature is invalid", ErrInvalidToken)
+	ErrTokenMalformed        = fmt.Errorf("%w: token is malformed", ErrInvalidToken)
+	ErrTokenRevoked          = fmt.Errorf("%w: token has been revoked", ErrInvalidToken)
+	ErrTokenAudienceInvalid  = fmt.Errorf("%w: token was not issued for this audience", ErrInvalidToken)
+)
@@ -3,8 +3,15 @@ package errors
 import "errors"
 
 var (
-	ErrUserNotFound       = errors.New("user not found")
-	ErrUserEmailExists    = errors.New("user with this email already exists")
-	ErrUserUsernameExists = errors.New("user with this username already exists")
-	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrUserEmailExists           = errors.New("user with this email already exists")
+	ErrUserUsernameExists        = errors.New("user with this username already exists")
+	ErrInvalidCredentials        = errors.New("invalid login or password")
+	ErrEmailNotVerified          = errors.New("email address is not verified")
+	ErrInvalidVerificationToken  = errors.New("verification token is invalid or has expired")
+	ErrInvalidPasswordResetToken = errors.New("password reset token is invalid or has expired")
+	ErrInvalidEmailChangeToken   = errors.New("email change token is invalid or has expired")
+	ErrEmailUnchanged            = errors.New("new email is the current one")
+	ErrAccountSuspended          = errors.New("account is suspended")
+	ErrInvalidUnlockToken        = errors.New("unlock token is invalid or has expired")
 )
@@ -0,0 +1,14 @@
+package models
+
+// JSONWebKey is the public part of a signing key as published in a JWKS
+type JSONWebKey struct {
+	Kty string
+	Kid string
+	Use string
+	Alg string
+	N   string
+	E   string
+	Crv string
+	X   string
+	Y   string
+}
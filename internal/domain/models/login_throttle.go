@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginWindow is a sliding window of failed logins kept under Key. Once it
+// holds MaxFailures of them, further attempts have to back off.
+type LoginWindow struct {
+	Key         string
+	MaxFailures int
+}
+
+// LoginBackoff is the time to wait after the latest failure of a full
+// window. It starts at Base and doubles with every further failure up to Max.
+type LoginBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/google/uuid"
+
+// OneTimeToken is the server-side state of a token mailed to a user to
+// confirm an action. Email is the address the token was sent to.
+type OneTimeToken struct {
+	UserID uuid.UUID
+	Email  string
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a single login of a user on a device. Access and refresh tokens
+// issued for the login carry its ID.
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ClientIP   string
+	UserAgent  string
+	Audience   string
+	Current    bool
+}
+
+// ClientInfo describes the client a request came from. Audience is the
+// application the client asks tokens for.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Audience  string
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SigningKey is a persisted token signing key. A zero ActivatesAt means the
+// key is only published for verification and has not been promoted yet.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	KeyPEM      []byte
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenPair is what a client receives after a successful login or refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// RefreshToken is the server-side state of an opaque refresh token.
+// Every token issued from a single login shares the ID of its session.
+type RefreshToken struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Rotated   bool
+}
+
+// TokenClaims are the claims of a validated access token. Tokens of users
+// who have not verified their email address are limited.
+type TokenClaims struct {
+	UserID        uuid.UUID
+	Email         string
+	Username      string
+	EmailVerified bool
+	TokenID       string
+	SessionID     uuid.UUID
+	Audience      []string
+	Scope         string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
+
+// Introspection describes a token the way RFC 7662 does. An inactive token
+// carries no other information.
+type Introspection struct {
+	Active    bool
+	TokenType string
+	UserID    uuid.UUID
+	ClientID  string
+	TokenID   string
+	Audience  []string
+	Scope     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Session   *Session
+}
@@ -1,9 +1,62 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
 
+	"github.com/google/uuid"
+)
+
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+)
+
+// User is an account. PendingEmail is the address the user asked to move
+// to and has not confirmed yet. DeletedAt is set while the account waits
+// to be purged. A suspension without SuspendedUntil lasts until it is lifted.
+// PepperVersion is the version of the pepper PassHash was made with.
+// FailedLogins counts the recent consecutive failed logins that have not
+// locked the account yet.
 type User struct {
-	ID       uuid.UUID `db:"id"`
-	Email    string    `db:"email"`
-	PassHash []byte    `db:"password_hash"`
+	ID               uuid.UUID `db:"id"`
+	Email            string    `db:"email"`
+	Username         string    `db:"username"`
+	PassHash         []byte    `db:"password_hash"`
+	PepperVersion    int       `db:"pepper_version"`
+	VerifiedAt       time.Time `db:"verified_at"`
+	PendingEmail     string    `db:"pending_email"`
+	DeletedAt        time.Time `db:"deleted_at"`
+	Status           string    `db:"status"`
+	SuspensionReason string    `db:"suspension_reason"`
+	SuspendedUntil   time.Time `db:"suspended_until"`
+	CreatedAt        time.Time `db:"created_at"`
+	FailedLogins     int       `db:"failed_login_count"`
+	LockedUntil      time.Time `db:"locked_until"`
+}
+
+// PasswordHash is a stored password hash along with the version of the
+// pepper it was made with
+type PasswordHash struct {
+	Hash          []byte `db:"password_hash"`
+	PepperVersion int    `db:"pepper_version"`
+}
+
+// EmailVerified reports whether the user confirmed the email address
+func (u *User) EmailVerified() bool {
+	return !u.VerifiedAt.IsZero()
+}
+
+// Deleted reports whether the account is waiting to be purged
+func (u *User) Deleted() bool {
+	return !u.DeletedAt.IsZero()
+}
+
+// Suspended reports whether the account is suspended at the moment
+func (u *User) Suspended() bool {
+	return u.Status == UserStatusSuspended && (u.SuspendedUntil.IsZero() || time.Now().Before(u.SuspendedUntil))
+}
+
+// Locked reports whether the account is locked after failed logins at the moment
+func (u *User) Locked() bool {
+	return time.Now().Before(u.LockedUntil)
 }
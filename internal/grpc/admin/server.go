@@ -0,0 +1,258 @@
+package admin
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	authGrpc "auth-service/internal/grpc/auth"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const adminTokenHeader = "x-admin-token"
+
+type Keys interface {
+	AddSigningKey(ctx context.Context, algorithm string) (*models.SigningKey, error)
+	PromoteSigningKey(ctx context.Context, kid string) error
+	ListSigningKeys(ctx context.Context) ([]*models.SigningKey, error)
+}
+
+type Sessions interface {
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]*models.Session, error)
+	RevokeUserSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+}
+
+type Accounts interface {
+	SuspendUser(ctx context.Context, userID uuid.UUID, reason string, until time.Time) error
+	UnsuspendUser(ctx context.Context, userID uuid.UUID) error
+	UnlockUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type ServerApi struct {
+	authService.UnimplementedAdminServiceServer
+	keys     Keys
+	sessions Sessions
+	accounts Accounts
+}
+
+func Register(grpcServer *grpc.Server, keys Keys, sessions Sessions, accounts Accounts) {
+	authService.RegisterAdminServiceServer(grpcServer, &ServerApi{keys: keys, sessions: sessions, accounts: accounts})
+}
+
+// UnaryInterceptor rejects admin calls that do not carry the configured admin token.
+// An empty token disables the admin API.
+func UnaryInterceptor(adminToken string) grpc.UnaryServerInterceptor {
+	prefix := "/" + authService.AdminService_ServiceDesc.ServiceName + "/"
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(ctx, req)
+		}
+
+		if adminToken == "" {
+			return nil, status.Error(codes.PermissionDenied, "admin API is disabled")
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(adminTokenHeader)
+		if len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(adminToken)) != 1 {
+			return nil, status.Error(codes.PermissionDenied, "admin token is invalid")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (s *ServerApi) AddSigningKey(ctx context.Context, req *authService.AddSigningKeyRequest) (*authService.SigningKey, error) {
+	if req.Algorithm == "" {
+		return nil, status.Error(codes.InvalidArgument, "Algorithm is required")
+	}
+
+	key, err := s.keys.AddSigningKey(ctx, req.Algorithm)
+	if err != nil {
+		log.Printf("failed to add signing key: %v", err)
+
+		switch {
+		case errors.Is(err, domain_errors.ErrUnsupportedAlgorithm):
+			return nil, status.Error(codes.InvalidArgument, domain_errors.ErrUnsupportedAlgorithm.Error())
+		case errors.Is(err, domain_errors.ErrKeyEncryptionMissing):
+			return nil, status.Error(codes.FailedPrecondition, domain_errors.ErrKeyEncryptionMissing.Error())
+		default:
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+	}
+
+	return toSigningKey(key), nil
+}
+
+func (s *ServerApi) PromoteSigningKey(ctx context.Context, req *authService.PromoteSigningKeyRequest) (*emptypb.Empty, error) {
+	if req.Kid == "" {
+		return nil, status.Error(codes.InvalidArgument, "Kid is required")
+	}
+
+	if err := s.keys.PromoteSigningKey(ctx, req.Kid); err != nil {
+		log.Printf("failed to promote signing key: %v", err)
+
+		if errors.Is(err, domain_errors.ErrSigningKeyNotFound) {
+			return nil, status.Error(codes.NotFound, domain_errors.ErrSigningKeyNotFound.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) ListSigningKeys(ctx context.Context, req *emptypb.Empty) (*authService.ListSigningKeysResponse, error) {
+	keys, err := s.keys.ListSigningKeys(ctx)
+	if err != nil {
+		log.Printf("failed to list signing keys: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	response := &authService.ListSigningKeysResponse{
+		Keys: make([]*authService.SigningKey, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		response.Keys = append(response.Keys, toSigningKey(key))
+	}
+
+	return response, nil
+}
+
+func (s *ServerApi) ListUserSessions(ctx context.Context, req *authService.UserIdRequest) (*authService.ListSessionsResponse, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+	}
+
+	sessions, err := s.sessions.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, authGrpc.SessionError(err)
+	}
+
+	return authGrpc.SessionsResponse(sessions), nil
+}
+
+func (s *ServerApi) RevokeUserSession(ctx context.Context, req *authService.RevokeUserSessionRequest) (*emptypb.Empty, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+	}
+
+	sessionID, err := uuid.Parse(req.SessionId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Session id is invalid")
+	}
+
+	if err = s.sessions.RevokeUserSession(ctx, userID, sessionID); err != nil {
+		return nil, authGrpc.SessionError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) RevokeAllUserSessions(ctx context.Context, req *authService.UserIdRequest) (*emptypb.Empty, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+	}
+
+	if err = s.sessions.RevokeUserSessions(ctx, userID); err != nil {
+		return nil, authGrpc.SessionError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) SuspendUser(ctx context.Context, req *authService.SuspendUserRequest) (*emptypb.Empty, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+	}
+
+	if req.Reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "Reason is required")
+	}
+
+	var until time.Time
+	if req.ExpiresAt != nil {
+		until = req.ExpiresAt.AsTime()
+		if !until.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "Expiry must be in the future")
+		}
+	}
+
+	if err = s.accounts.SuspendUser(ctx, userID, req.Reason, until); err != nil {
+		return nil, accountError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) UnsuspendUser(ctx context.Context, req *authService.UserIdRequest) (*emptypb.Empty, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+	}
+
+	if err = s.accounts.UnsuspendUser(ctx, userID); err != nil {
+		return nil, accountError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) UnlockUser(ctx context.Context, req *authService.UserIdRequest) (*emptypb.Empty, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+	}
+
+	if err = s.accounts.UnlockUser(ctx, userID); err != nil {
+		return nil, accountError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func accountError(err error) error {
+	log.Printf("failed to update account: %v", err)
+
+	if errors.Is(err, domain_errors.ErrUserNotFound) {
+		return status.Error(codes.NotFound, domain_errors.ErrUserNotFound.Error())
+	}
+
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func toSigningKey(key *models.SigningKey) *authService.SigningKey {
+	return &authService.SigningKey{
+		Kid:         key.ID,
+		Algorithm:   key.Algorithm,
+		CreatedAt:   toTimestamp(key.CreatedAt),
+		ActivatesAt: toTimestamp(key.ActivatesAt),
+		RetiresAt:   toTimestamp(key.RetiresAt),
+	}
+}
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
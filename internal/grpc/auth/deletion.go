@@ -0,0 +1,67 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"context"
+	"errors"
+	"log"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *ServerApi) DeleteAccount(ctx context.Context, req *authService.DeleteAccountRequest) (*emptypb.Empty, error) {
+	if req.JwtToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "Password is required")
+	}
+
+	if err := s.auth.DeleteAccount(ctx, req.JwtToken, req.Password, s.clientInfo(ctx)); err != nil {
+		if tokenErr := tokenError(err); tokenErr != nil {
+			return nil, tokenErr
+		}
+
+		log.Printf("failed to delete account: %v", err)
+
+		switch {
+		case errors.Is(err, domain_errors.ErrTooManyLoginAttempts):
+			return nil, throttledError(err)
+		case errors.Is(err, domain_errors.ErrInvalidCredentials):
+			return nil, status.Error(codes.PermissionDenied, domain_errors.ErrInvalidCredentials.Error())
+		default:
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) RestoreAccount(ctx context.Context, req *authService.RestoreAccountRequest) (*emptypb.Empty, error) {
+	if req.Identifier == "" {
+		return nil, status.Error(codes.InvalidArgument, "Email or username is required")
+	}
+
+	if req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "Password is required")
+	}
+
+	if err := s.auth.RestoreAccount(ctx, req.Identifier, req.Password, s.clientInfo(ctx)); err != nil {
+		log.Printf("failed to restore account: %v", err)
+
+		switch {
+		case errors.Is(err, domain_errors.ErrTooManyLoginAttempts):
+			return nil, throttledError(err)
+		case errors.Is(err, domain_errors.ErrInvalidCredentials):
+			return nil, status.Error(codes.Unauthenticated, domain_errors.ErrInvalidCredentials.Error())
+		default:
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
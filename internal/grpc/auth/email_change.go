@@ -0,0 +1,83 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *ServerApi) ChangeEmail(ctx context.Context, req *authService.ChangeEmailRequest) (*emptypb.Empty, error) {
+	if req.JwtToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if req.NewEmail == "" {
+		return nil, status.Error(codes.InvalidArgument, "Email is required")
+	}
+
+	if _, err := mail.ParseAddress(req.NewEmail); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.auth.ChangeEmail(ctx, req.JwtToken, req.NewEmail); err != nil {
+		if tokenErr := tokenError(err); tokenErr != nil {
+			return nil, tokenErr
+		}
+
+		return nil, emailChangeError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) ConfirmEmailChange(
+	ctx context.Context,
+	req *authService.EmailChangeTokenRequest,
+) (*emptypb.Empty, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if err := s.auth.ConfirmEmailChange(ctx, req.Token); err != nil {
+		return nil, emailChangeError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) RevertEmailChange(
+	ctx context.Context,
+	req *authService.EmailChangeTokenRequest,
+) (*emptypb.Empty, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if err := s.auth.RevertEmailChange(ctx, req.Token); err != nil {
+		return nil, emailChangeError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func emailChangeError(err error) error {
+	log.Printf("failed to change email: %v", err)
+
+	switch {
+	case errors.Is(err, domain_errors.ErrUserEmailExists):
+		return status.Error(codes.AlreadyExists, domain_errors.ErrUserEmailExists.Error())
+	case errors.Is(err, domain_errors.ErrEmailUnchanged):
+		return status.Error(codes.InvalidArgument, domain_errors.ErrEmailUnchanged.Error())
+	case errors.Is(err, domain_errors.ErrInvalidEmailChangeToken):
+		return status.Error(codes.InvalidArgument, domain_errors.ErrInvalidEmailChangeToken.Error())
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain identifies this service in google.rpc.ErrorInfo details
+const errorDomain = "auth.smap"
+
+// tokenErrorReasons maps the reasons a token is rejected to the ErrorInfo
+// reasons clients act upon. Most specific errors come first, as all of them
+// also match ErrInvalidToken.
+var tokenErrorReasons = []struct {
+	err    error
+	reason string
+}{
+	{domain_errors.ErrTokenExpired, "TOKEN_EXPIRED"},
+	{domain_errors.ErrTokenNotYetValid, "TOKEN_NOT_YET_VALID"},
+	{domain_errors.ErrTokenSignatureInvalid, "TOKEN_SIGNATURE_INVALID"},
+	{domain_errors.ErrTokenMalformed, "TOKEN_MALFORMED"},
+	{domain_errors.ErrTokenRevoked, "TOKEN_REVOKED"},
+	{domain_errors.ErrTokenAudienceInvalid, "TOKEN_AUDIENCE_INVALID"},
+	{domain_errors.ErrInvalidToken, "TOKEN_INVALID"},
+}
+
+// passwordErrorReasons maps the password policy rules to the reasons of
+// BadRequest field violations
+var passwordErrorReasons = []struct {
+	err    error
+	reason string
+}{
+	{domain_errors.ErrPasswordTooShort, "PASSWORD_TOO_SHORT"},
+	{domain_errors.ErrPasswordTooLong, "PASSWORD_TOO_LONG"},
+	{domain_errors.ErrPasswordMissingCharacterClass, "PASSWORD_MISSING_CHARACTER_CLASS"},
+	{domain_errors.ErrPasswordTooWeak, "PASSWORD_TOO_WEAK"},
+	{domain_errors.ErrPasswordContainsPersonalInfo, "PASSWORD_CONTAINS_PERSONAL_INFO"},
+	{domain_errors.ErrPasswordBreached, "PASSWORD_BREACHED"},
+	{domain_errors.ErrPasswordReused, "PASSWORD_REUSED"},
+}
+
+// tokenError turns a rejected token into an Unauthenticated status carrying
+// the reason as an ErrorInfo detail. It returns nil for other errors.
+func tokenError(err error) error {
+	for _, r := range tokenErrorReasons {
+		if !errors.Is(err, r.err) {
+			continue
+		}
+
+		st := status.New(codes.Unauthenticated, "Token is Invalid")
+		if detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason: r.reason,
+			Domain: errorDomain,
+		}); detailsErr == nil {
+			st = detailed
+		}
+
+		return st.Err()
+	}
+
+	return nil
+}
+
+// throttledError turns a throttled login into a ResourceExhausted status
+// carrying the time to wait as a RetryInfo detail
+func throttledError(err error) error {
+	st := status.New(codes.ResourceExhausted, domain_errors.ErrTooManyLoginAttempts.Error())
+
+	var throttled *domain_errors.LoginThrottledError
+	if errors.As(err, &throttled) {
+		if detailed, detailsErr := st.WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(throttled.RetryAfter),
+		}); detailsErr == nil {
+			st = detailed
+		}
+	}
+
+	return st.Err()
+}
+
+// passwordViolations splits a rejected password into the rules it breaks. It
+// returns nil for other errors.
+func passwordViolations(err error) []error {
+	var policyErr *domain_errors.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Violations
+	}
+
+	// The hasher can reject a password on its own
+	if errors.Is(err, domain_errors.ErrPasswordTooLong) {
+		return []error{domain_errors.ErrPasswordTooLong}
+	}
+
+	return nil
+}
+
+func passwordViolationReason(violation error) string {
+	for _, r := range passwordErrorReasons {
+		if errors.Is(violation, r.err) {
+			return r.reason
+		}
+	}
+
+	return "PASSWORD_INVALID"
+}
+
+// passwordError turns a rejected password into an InvalidArgument status
+// carrying a BadRequest detail with a violation of the field per broken
+// rule. It returns nil for other errors.
+func passwordError(err error, field string) error {
+	violations := passwordViolations(err)
+	if violations == nil {
+		return nil
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: violation.Error(),
+			Reason:      passwordViolationReason(violation),
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, "Password does not meet the policy")
+	if detailed, detailsErr := st.WithDetails(badRequest); detailsErr == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
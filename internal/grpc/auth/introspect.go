@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"auth-service/internal/domain/models"
+	"context"
+	"log"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IntrospectToken is only open to services presenting the service token, as
+// RFC 7662 requires the endpoint to authorize its callers
+func (s *ServerApi) IntrospectToken(
+	ctx context.Context,
+	req *authService.IntrospectTokenRequest,
+) (*authService.IntrospectTokenResponse, error) {
+	if !s.isTrustedService(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "Service token is required")
+	}
+
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	introspection, err := s.auth.IntrospectToken(ctx, req.Token)
+	if err != nil {
+		log.Printf("failed to introspect token: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return introspectionResponse(introspection), nil
+}
+
+// introspectionResponse converts an introspection result into its RFC 7662 shaped message
+func introspectionResponse(introspection *models.Introspection) *authService.IntrospectTokenResponse {
+	if !introspection.Active {
+		return &authService.IntrospectTokenResponse{}
+	}
+
+	response := &authService.IntrospectTokenResponse{
+		Active:    true,
+		TokenType: introspection.TokenType,
+		ClientId:  introspection.ClientID,
+		Scope:     introspection.Scope,
+		Jti:       introspection.TokenID,
+		Aud:       introspection.Audience,
+	}
+
+	if introspection.UserID != uuid.Nil {
+		response.Sub = introspection.UserID.String()
+	}
+
+	if !introspection.IssuedAt.IsZero() {
+		response.Iat = introspection.IssuedAt.Unix()
+	}
+
+	if !introspection.ExpiresAt.IsZero() {
+		response.Exp = introspection.ExpiresAt.Unix()
+	}
+
+	if introspection.Session != nil {
+		response.Session = toSession(introspection.Session)
+	}
+
+	return response
+}
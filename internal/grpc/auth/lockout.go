@@ -0,0 +1,30 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"context"
+	"errors"
+	"log"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *ServerApi) UnlockAccount(ctx context.Context, req *authService.UnlockAccountRequest) (*emptypb.Empty, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if err := s.auth.UnlockAccount(ctx, req.Token); err != nil {
+		log.Printf("failed to unlock account: %v", err)
+
+		if errors.Is(err, domain_errors.ErrInvalidUnlockToken) {
+			return nil, status.Error(codes.InvalidArgument, domain_errors.ErrInvalidUnlockToken.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"context"
+	"errors"
+	"log"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *ServerApi) ChangePassword(ctx context.Context, req *authService.ChangePasswordRequest) (*emptypb.Empty, error) {
+	if req.JwtToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "Password is required")
+	}
+
+	if err := s.auth.ChangePassword(ctx, req.JwtToken, req.CurrentPassword, req.NewPassword, s.clientInfo(ctx)); err != nil {
+		if tokenErr := tokenError(err); tokenErr != nil {
+			return nil, tokenErr
+		}
+
+		log.Printf("failed to change password: %v", err)
+
+		if passwordErr := passwordError(err, "new_password"); passwordErr != nil {
+			return nil, passwordErr
+		}
+
+		switch {
+		case errors.Is(err, domain_errors.ErrTooManyLoginAttempts):
+			return nil, throttledError(err)
+		case errors.Is(err, domain_errors.ErrInvalidCredentials):
+			return nil, status.Error(codes.PermissionDenied, domain_errors.ErrInvalidCredentials.Error())
+		default:
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// CheckPasswordStrength reports how the password fares against the policy,
+// so that clients can give feedback while it is typed
+func (s *ServerApi) CheckPasswordStrength(
+	ctx context.Context,
+	req *authService.CheckPasswordStrengthRequest,
+) (*authService.CheckPasswordStrengthResponse, error) {
+	entropy, err := s.auth.CheckPasswordStrength(req.Password, req.Email, req.Username)
+
+	violations := passwordViolations(err)
+	if err != nil && violations == nil {
+		log.Printf("failed to check password strength: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	response := &authService.CheckPasswordStrengthResponse{
+		Acceptable:  err == nil,
+		EntropyBits: entropy,
+		Violations:  make([]*authService.PasswordViolation, 0, len(violations)),
+	}
+
+	for _, violation := range violations {
+		response.Violations = append(response.Violations, &authService.PasswordViolation{
+			Reason:      passwordViolationReason(violation),
+			Description: violation.Error(),
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *ServerApi) RequestPasswordReset(
+	ctx context.Context,
+	req *authService.RequestPasswordResetRequest,
+) (*emptypb.Empty, error) {
+	if req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "Email is required")
+	}
+
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.auth.RequestPasswordReset(ctx, req.Email); err != nil {
+		log.Printf("failed to request password reset: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) ConfirmPasswordReset(
+	ctx context.Context,
+	req *authService.ConfirmPasswordResetRequest,
+) (*emptypb.Empty, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if req.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "Password is required")
+	}
+
+	if err := s.auth.ConfirmPasswordReset(ctx, req.Token, req.NewPassword); err != nil {
+		log.Printf("failed to reset password: %v", err)
+
+		if passwordErr := passwordError(err, "new_password"); passwordErr != nil {
+			return nil, passwordErr
+		}
+
+		switch {
+		case errors.Is(err, domain_errors.ErrInvalidPasswordResetToken):
+			return nil, status.Error(codes.InvalidArgument, domain_errors.ErrInvalidPasswordResetToken.Error())
+		default:
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
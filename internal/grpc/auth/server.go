@@ -2,10 +2,14 @@ package auth
 
 import (
 	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
 	"context"
 	"errors"
 	"log"
 	"net/mail"
+	"net/netip"
+	"regexp"
+	"strings"
 
 	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
 	"github.com/google/uuid"
@@ -13,8 +17,30 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+const (
+	usernameMinLength = 3
+	usernameMaxLength = 32
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.]+$`)
+
+// reservedUsernames could be mistaken for the service or its staff
+var reservedUsernames = map[string]struct{}{
+	"admin":         {},
+	"administrator": {},
+	"root":          {},
+	"system":        {},
+	"support":       {},
+	"moderator":     {},
+	"smap":          {},
+	"api":           {},
+	"auth":          {},
+	"me":            {},
+}
+
 type Auth interface {
 	Register(
 		ctx context.Context,
@@ -24,24 +50,59 @@ type Auth interface {
 	) (userId uuid.UUID, err error)
 	Login(
 		ctx context.Context,
-		email string,
+		identifier string,
 		password string,
-	) (token string, err error)
+		client models.ClientInfo,
+	) (tokens *models.TokenPair, err error)
+	RefreshToken(
+		ctx context.Context,
+		refreshToken string,
+	) (tokens *models.TokenPair, err error)
 	ValidateToken(
 		token string,
-	) (userId uuid.UUID)
+		audience string,
+	) (claims *models.TokenClaims, err error)
 	Logout(
 		token string,
 	) error
+	Jwks() []models.JSONWebKey
+	ListSessions(ctx context.Context, token string) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, token string, sessionID uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, token string) error
+	IntrospectToken(ctx context.Context, token string) (*models.Introspection, error)
+	SendVerificationEmail(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ConfirmPasswordReset(ctx context.Context, token string, password string) error
+	ChangePassword(ctx context.Context, token string, currentPassword string, newPassword string, client models.ClientInfo) error
+	ChangeEmail(ctx context.Context, token string, email string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	RevertEmailChange(ctx context.Context, token string) error
+	DeleteAccount(ctx context.Context, token string, password string, client models.ClientInfo) error
+	RestoreAccount(ctx context.Context, identifier string, password string, client models.ClientInfo) error
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	BatchGetUsers(ctx context.Context, ids []uuid.UUID) ([]*models.User, error)
+	CheckPasswordStrength(password string, email string, username string) (float64, error)
+	UnlockAccount(ctx context.Context, token string) error
 }
 
 type ServerApi struct {
 	authService.UnimplementedAuthServiceServer
-	auth Auth
+	auth           Auth
+	serviceToken   string
+	trustedProxies []netip.Prefix
 }
 
-func Register(grpcServer *grpc.Server, auth Auth) {
-	authService.RegisterAuthServiceServer(grpcServer, &ServerApi{auth: auth})
+// Register adds the auth service to the server. Callers presenting the
+// service token may introspect tokens and read private user fields, an
+// empty token disables both. Only calls coming through one of the trusted
+// proxies may name the client address in x-forwarded-for.
+func Register(grpcServer *grpc.Server, auth Auth, serviceToken string, trustedProxies []netip.Prefix) {
+	authService.RegisterAuthServiceServer(grpcServer, &ServerApi{
+		auth:           auth,
+		serviceToken:   serviceToken,
+		trustedProxies: trustedProxies,
+	})
 }
 
 func (s *ServerApi) CreateUser(
@@ -56,11 +117,15 @@ func (s *ServerApi) CreateUser(
 	if err != nil {
 		log.Printf("failed to register user: %v", err)
 
+		if passwordErr := passwordError(err, "password"); passwordErr != nil {
+			return nil, passwordErr
+		}
+
 		switch {
 		case errors.Is(err, domain_errors.ErrUserEmailExists):
-			return nil, status.Errorf(codes.AlreadyExists, domain_errors.ErrUserEmailExists.Error())
+			return nil, status.Error(codes.AlreadyExists, domain_errors.ErrUserEmailExists.Error())
 		case errors.Is(err, domain_errors.ErrUserUsernameExists):
-			return nil, status.Errorf(codes.AlreadyExists, domain_errors.ErrUserUsernameExists.Error())
+			return nil, status.Error(codes.AlreadyExists, domain_errors.ErrUserUsernameExists.Error())
 		default:
 			return nil, status.Errorf(codes.Internal, "internal server error")
 		}
@@ -77,22 +142,64 @@ func (s *ServerApi) Login(ctx context.Context, req *authService.LoginRequest) (*
 		return nil, err
 	}
 
-	token, err := s.auth.Login(ctx, req.Email, req.Password)
+	client := s.clientInfo(ctx)
+	client.Audience = req.Audience
+
+	tokens, err := s.auth.Login(ctx, loginIdentifier(req), req.Password, client)
 	if err != nil {
 		log.Printf("failed to login: %v", err)
 
 		switch {
+		case errors.Is(err, domain_errors.ErrTooManyLoginAttempts):
+			return nil, throttledError(err)
+		case errors.Is(err, domain_errors.ErrInvalidAudience):
+			return nil, status.Error(codes.InvalidArgument, domain_errors.ErrInvalidAudience.Error())
+		case errors.Is(err, domain_errors.ErrEmailNotVerified):
+			return nil, status.Error(codes.FailedPrecondition, domain_errors.ErrEmailNotVerified.Error())
+		case errors.Is(err, domain_errors.ErrAccountSuspended):
+			return nil, status.Error(codes.PermissionDenied, domain_errors.ErrAccountSuspended.Error())
 		case errors.Is(err, domain_errors.ErrInvalidCredentials):
-			return nil, status.Errorf(codes.Unauthenticated, domain_errors.ErrInvalidCredentials.Error())
+			return nil, status.Error(codes.Unauthenticated, domain_errors.ErrInvalidCredentials.Error())
 		case errors.Is(err, domain_errors.ErrUserNotFound):
-			return nil, status.Errorf(codes.Unauthenticated, domain_errors.ErrInvalidCredentials.Error())
+			return nil, status.Error(codes.Unauthenticated, domain_errors.ErrInvalidCredentials.Error())
 		default:
 			return nil, status.Errorf(codes.Internal, "internal server error")
 		}
 	}
 
 	return &authService.LoginResponse{
-		JwtToken: token,
+		JwtToken:     tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}, nil
+}
+
+func (s *ServerApi) RefreshToken(
+	ctx context.Context,
+	req *authService.RefreshTokenRequest,
+) (*authService.RefreshTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Refresh token is empty")
+	}
+
+	tokens, err := s.auth.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		log.Printf("failed to refresh token: %v", err)
+
+		switch {
+		case errors.Is(err, domain_errors.ErrInvalidRefreshToken):
+			return nil, status.Error(codes.Unauthenticated, domain_errors.ErrInvalidRefreshToken.Error())
+		case errors.Is(err, domain_errors.ErrRefreshTokenReused):
+			return nil, status.Error(codes.Unauthenticated, domain_errors.ErrInvalidRefreshToken.Error())
+		default:
+			return nil, status.Errorf(codes.Internal, "internal server error")
+		}
+	}
+
+	return &authService.RefreshTokenResponse{
+		JwtToken:     tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
 	}, nil
 }
 
@@ -101,15 +208,37 @@ func (s *ServerApi) ValidateToken(ctx context.Context, req *authService.TokenReq
 		return nil, status.Errorf(codes.InvalidArgument, "Token is empty")
 	}
 
-	userId := s.auth.ValidateToken(req.JwtToken)
+	claims, err := s.auth.ValidateToken(req.JwtToken, req.Audience)
+	if err != nil {
+		if tokenErr := tokenError(err); tokenErr != nil {
+			return nil, tokenErr
+		}
+
+		log.Printf("failed to validate token: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	response := &authService.UserResponse{
+		UserId:        claims.UserID.String(),
+		TokenId:       claims.TokenID,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Audience:      claims.Audience,
+	}
 
-	if userId == uuid.Nil {
-		return nil, status.Errorf(codes.Unauthenticated, "Token is Invalid")
+	if claims.SessionID != uuid.Nil {
+		response.SessionId = claims.SessionID.String()
 	}
 
-	return &authService.UserResponse{
-		UserId: userId.String(),
-	}, nil
+	if !claims.IssuedAt.IsZero() {
+		response.IssuedAt = timestamppb.New(claims.IssuedAt)
+	}
+
+	if !claims.ExpiresAt.IsZero() {
+		response.ExpiresAt = timestamppb.New(claims.ExpiresAt)
+	}
+
+	return response, nil
 }
 
 func (s *ServerApi) Logout(ctx context.Context, req *authService.TokenRequest) (*emptypb.Empty, error) {
@@ -127,6 +256,30 @@ func (s *ServerApi) Logout(ctx context.Context, req *authService.TokenRequest) (
 	return nil, nil
 }
 
+func (s *ServerApi) GetJwks(ctx context.Context, req *emptypb.Empty) (*authService.JwksResponse, error) {
+	keys := s.auth.Jwks()
+
+	response := &authService.JwksResponse{
+		Keys: make([]*authService.Jwk, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		response.Keys = append(response.Keys, &authService.Jwk{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		})
+	}
+
+	return response, nil
+}
+
 func validateRegisterData(req *authService.CreateUserRequest) error {
 	if req.Email == "" {
 		return status.Error(codes.InvalidArgument, "Email is required")
@@ -138,6 +291,10 @@ func validateRegisterData(req *authService.CreateUserRequest) error {
 		return status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if err = validateUsername(req.Username); err != nil {
+		return err
+	}
+
 	if req.Password == "" {
 		return status.Error(codes.InvalidArgument, "Password is required")
 	}
@@ -145,14 +302,47 @@ func validateRegisterData(req *authService.CreateUserRequest) error {
 	return nil
 }
 
+func validateUsername(username string) error {
+	if username == "" {
+		return status.Error(codes.InvalidArgument, "Username is required")
+	}
+
+	if len(username) < usernameMinLength || len(username) > usernameMaxLength {
+		return status.Errorf(codes.InvalidArgument, "Username must be between %d and %d characters long", usernameMinLength, usernameMaxLength)
+	}
+
+	if !usernamePattern.MatchString(username) {
+		return status.Error(codes.InvalidArgument, "Username may only contain letters, digits, underscores and dots")
+	}
+
+	if strings.HasPrefix(username, ".") || strings.HasSuffix(username, ".") || strings.Contains(username, "..") {
+		return status.Error(codes.InvalidArgument, "Username can not start or end with a dot or contain consecutive dots")
+	}
+
+	if _, reserved := reservedUsernames[strings.ToLower(username)]; reserved {
+		return status.Error(codes.InvalidArgument, "Username is reserved")
+	}
+
+	return nil
+}
+
 func validateLoginData(req *authService.LoginRequest) error {
 	if req.Password == "" {
 		return status.Error(codes.InvalidArgument, "Password is required")
 	}
 
-	if req.Email == "" {
-		return status.Error(codes.InvalidArgument, "Email is required")
+	if loginIdentifier(req) == "" {
+		return status.Error(codes.InvalidArgument, "Email or username is required")
 	}
 
 	return nil
 }
+
+// loginIdentifier falls back to the email field for clients that predate identifier
+func loginIdentifier(req *authService.LoginRequest) string {
+	if req.Identifier != "" {
+		return req.Identifier
+	}
+
+	return req.Email
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"auth-service/internal/domain/models"
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// mockAuth implements the calls a test stubs. The embedded interface is
+// nil, so any other call panics.
+type mockAuth struct {
+	mock.Mock
+	Auth
+}
+
+func (m *mockAuth) IntrospectToken(ctx context.Context, token string) (*models.Introspection, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.Introspection), args.Error(1)
+}
+
+func withServiceToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(serviceTokenHeader, token))
+}
+
+func requireCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, code, status.Code(err))
+}
+
+func TestServerApi_IntrospectToken_RequiresServiceToken(t *testing.T) {
+	auth := new(mockAuth)
+	server := &ServerApi{auth: auth, serviceToken: "service-secret"}
+	req := &authService.IntrospectTokenRequest{Token: "token"}
+
+	_, err := server.IntrospectToken(context.Background(), req)
+	requireCode(t, err, codes.Unauthenticated)
+
+	_, err = server.IntrospectToken(withServiceToken("wrong"), req)
+	requireCode(t, err, codes.Unauthenticated)
+
+	auth.AssertNotCalled(t, "IntrospectToken", mock.Anything, mock.Anything)
+}
+
+func TestServerApi_IntrospectToken_TrustedService(t *testing.T) {
+	auth := new(mockAuth)
+	server := &ServerApi{auth: auth, serviceToken: "service-secret"}
+	ctx := withServiceToken("service-secret")
+	auth.On("IntrospectToken", ctx, "token").Return(&models.Introspection{Active: true, TokenType: "access_token"}, nil)
+
+	response, err := server.IntrospectToken(ctx, &authService.IntrospectTokenRequest{Token: "token"})
+
+	require.NoError(t, err)
+	require.True(t, response.Active)
+	require.Equal(t, "access_token", response.TokenType)
+}
+
+func TestServerApi_IntrospectToken_DisabledWithoutServiceToken(t *testing.T) {
+	server := &ServerApi{auth: new(mockAuth)}
+
+	_, err := server.IntrospectToken(withServiceToken(""), &authService.IntrospectTokenRequest{Token: "token"})
+
+	requireCode(t, err, codes.Unauthenticated)
+}
+
+func callFrom(remote string, forwardedFor ...string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: net.TCPAddrFromAddrPort(netip.MustParseAddrPort(remote))})
+
+	md := metadata.Pairs("user-agent", "test")
+	for _, header := range forwardedFor {
+		md.Append("x-forwarded-for", header)
+	}
+
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func TestServerApi_ClientInfo_SpoofedForwardedFor(t *testing.T) {
+	server := &ServerApi{trustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+
+	client := server.clientInfo(callFrom("203.0.113.7:41000", "198.51.100.1"))
+
+	require.Equal(t, "203.0.113.7", client.IP)
+	require.Equal(t, "test", client.UserAgent)
+}
+
+func TestServerApi_ClientInfo_NoTrustedProxies(t *testing.T) {
+	server := &ServerApi{}
+
+	client := server.clientInfo(callFrom("10.0.0.5:41000", "198.51.100.1"))
+
+	require.Equal(t, "10.0.0.5", client.IP)
+}
+
+func TestServerApi_ClientInfo_TrustedProxy(t *testing.T) {
+	server := &ServerApi{trustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.10/32"),
+	}}
+
+	// The client put a fake address in front, the proxies appended the real one
+	client := server.clientInfo(callFrom("10.0.0.5:41000", "198.51.100.1, 203.0.113.7, 192.0.2.10"))
+	require.Equal(t, "203.0.113.7", client.IP)
+
+	client = server.clientInfo(callFrom("10.0.0.5:41000", "198.51.100.1", "203.0.113.7"))
+	require.Equal(t, "203.0.113.7", client.IP)
+
+	// A malformed hop ends the walk at the last proxy
+	client = server.clientInfo(callFrom("10.0.0.5:41000", "203.0.113.7, not-an-ip"))
+	require.Equal(t, "10.0.0.5", client.IP)
+
+	client = server.clientInfo(callFrom("10.0.0.5:41000"))
+	require.Equal(t, "10.0.0.5", client.IP)
+}
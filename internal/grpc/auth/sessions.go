@@ -0,0 +1,162 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"errors"
+	"log"
+	"net/netip"
+	"strings"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *ServerApi) ListSessions(ctx context.Context, req *authService.TokenRequest) (*authService.ListSessionsResponse, error) {
+	if req.JwtToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Token is empty")
+	}
+
+	sessions, err := s.auth.ListSessions(ctx, req.JwtToken)
+	if err != nil {
+		return nil, SessionError(err)
+	}
+
+	return SessionsResponse(sessions), nil
+}
+
+func (s *ServerApi) RevokeSession(ctx context.Context, req *authService.RevokeSessionRequest) (*emptypb.Empty, error) {
+	if req.JwtToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Token is empty")
+	}
+
+	sessionID, err := uuid.Parse(req.SessionId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Session id is invalid")
+	}
+
+	if err = s.auth.RevokeSession(ctx, req.JwtToken, sessionID); err != nil {
+		return nil, SessionError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) RevokeAllSessions(ctx context.Context, req *authService.TokenRequest) (*emptypb.Empty, error) {
+	if req.JwtToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Token is empty")
+	}
+
+	if err := s.auth.RevokeAllSessions(ctx, req.JwtToken); err != nil {
+		return nil, SessionError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// SessionError maps errors of the session management calls to gRPC statuses
+func SessionError(err error) error {
+	if tokenErr := tokenError(err); tokenErr != nil {
+		return tokenErr
+	}
+
+	switch {
+	case errors.Is(err, domain_errors.ErrSessionNotFound):
+		return status.Error(codes.NotFound, domain_errors.ErrSessionNotFound.Error())
+	default:
+		log.Printf("failed to manage sessions: %v", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func SessionsResponse(sessions []*models.Session) *authService.ListSessionsResponse {
+	response := &authService.ListSessionsResponse{
+		Sessions: make([]*authService.Session, 0, len(sessions)),
+	}
+
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, toSession(session))
+	}
+
+	return response
+}
+
+func toSession(session *models.Session) *authService.Session {
+	return &authService.Session{
+		SessionId:  session.ID.String(),
+		CreatedAt:  timestamppb.New(session.CreatedAt),
+		LastSeenAt: timestamppb.New(session.LastSeenAt),
+		ClientIp:   session.ClientIP,
+		UserAgent:  session.UserAgent,
+		Current:    session.Current,
+	}
+}
+
+// clientInfo extracts the client address and user agent of the call. When
+// the call comes from a trusted proxy, the client is the nearest address in
+// x-forwarded-for that is not a trusted proxy itself. Anyone else could put
+// any address there, so their own address is used.
+func (s *ServerApi) clientInfo(ctx context.Context) models.ClientInfo {
+	var client models.ClientInfo
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		// Addresses other than ip:port, such as unix sockets, are kept as they are
+		client.IP = p.Addr.String()
+		if addr, err := netip.ParseAddrPort(client.IP); err == nil {
+			client.IP = s.forwardedFor(addr.Addr().Unmap(), md.Get("x-forwarded-for")).String()
+		}
+	}
+
+	if userAgent := md.Get("user-agent"); len(userAgent) > 0 {
+		client.UserAgent = userAgent[0]
+	}
+
+	return client
+}
+
+// forwardedFor walks the forwarding chain from the right for as long as the
+// hops are trusted proxies, and returns the address reached
+func (s *ServerApi) forwardedFor(peerAddr netip.Addr, headers []string) netip.Addr {
+	if !s.isTrustedProxy(peerAddr) {
+		return peerAddr
+	}
+
+	var hops []string
+	for _, header := range headers {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := peerAddr
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+
+		client = hop.Unmap()
+		if !s.isTrustedProxy(client) {
+			break
+		}
+	}
+
+	return client
+}
+
+func (s *ServerApi) isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const serviceTokenHeader = "x-service-token"
+
+// maxBatchGetUsers bounds the number of users a single BatchGetUsers call can resolve
+const maxBatchGetUsers = 100
+
+// userFields lists the User fields a read mask can select and whether
+// reading them requires the service token
+var userFields = map[string]bool{
+	"id":             false,
+	"username":       false,
+	"created_at":     false,
+	"email":          true,
+	"email_verified": true,
+}
+
+func (s *ServerApi) GetUserById(ctx context.Context, req *authService.GetUserByIdRequest) (*authService.User, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+	}
+
+	fields, err := s.readFields(ctx, req.ReadMask)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.auth.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, domain_errors.ErrUserNotFound.Error())
+		}
+
+		log.Printf("failed to get user: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return toUser(user, fields), nil
+}
+
+func (s *ServerApi) BatchGetUsers(
+	ctx context.Context,
+	req *authService.BatchGetUsersRequest,
+) (*authService.BatchGetUsersResponse, error) {
+	if len(req.UserIds) > maxBatchGetUsers {
+		return nil, status.Errorf(codes.InvalidArgument, "At most %d users can be requested at once", maxBatchGetUsers)
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.UserIds))
+	for _, value := range req.UserIds {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "User id is invalid")
+		}
+		ids = append(ids, id)
+	}
+
+	fields, err := s.readFields(ctx, req.ReadMask)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.auth.BatchGetUsers(ctx, ids)
+	if err != nil {
+		log.Printf("failed to get users: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	response := &authService.BatchGetUsersResponse{
+		Users: make([]*authService.User, 0, len(users)),
+	}
+
+	for _, user := range users {
+		response.Users = append(response.Users, toUser(user, fields))
+	}
+
+	return response, nil
+}
+
+// readFields resolves the read mask into the set of fields to return. An
+// empty mask selects every field the caller may read, while naming a field
+// the caller may not read is rejected.
+func (s *ServerApi) readFields(ctx context.Context, mask *fieldmaskpb.FieldMask) (map[string]bool, error) {
+	trusted := s.isTrustedService(ctx)
+	fields := make(map[string]bool, len(userFields))
+
+	if len(mask.GetPaths()) == 0 {
+		for field, private := range userFields {
+			if !private || trusted {
+				fields[field] = true
+			}
+		}
+		return fields, nil
+	}
+
+	for _, path := range mask.GetPaths() {
+		private, ok := userFields[path]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown field %q in read mask", path)
+		}
+
+		if private && !trusted {
+			return nil, status.Errorf(codes.PermissionDenied, "Reading %s requires a service token", path)
+		}
+
+		fields[path] = true
+	}
+
+	return fields, nil
+}
+
+// isTrustedService reports whether the call carries the configured service token
+func (s *ServerApi) isTrustedService(ctx context.Context) bool {
+	if s.serviceToken == "" {
+		return false
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(serviceTokenHeader)
+
+	return len(values) > 0 && subtle.ConstantTimeCompare([]byte(values[0]), []byte(s.serviceToken)) == 1
+}
+
+func toUser(user *models.User, fields map[string]bool) *authService.User {
+	response := &authService.User{}
+
+	if fields["id"] {
+		response.Id = user.ID.String()
+	}
+	if fields["username"] {
+		response.Username = user.Username
+	}
+	if fields["created_at"] {
+		response.CreatedAt = timestamppb.New(user.CreatedAt)
+	}
+	if fields["email"] {
+		response.Email = user.Email
+	}
+	if fields["email_verified"] {
+		response.EmailVerified = user.EmailVerified()
+	}
+
+	return response
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+
+	authService "github.com/NormVR/smap_protobuf/gen/services/auth_service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *ServerApi) SendVerificationEmail(
+	ctx context.Context,
+	req *authService.SendVerificationEmailRequest,
+) (*emptypb.Empty, error) {
+	if req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "Email is required")
+	}
+
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.auth.SendVerificationEmail(ctx, req.Email); err != nil {
+		log.Printf("failed to send verification email: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerApi) VerifyEmail(ctx context.Context, req *authService.VerifyEmailRequest) (*emptypb.Empty, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is empty")
+	}
+
+	if err := s.auth.VerifyEmail(ctx, req.Token); err != nil {
+		log.Printf("failed to verify email: %v", err)
+
+		if errors.Is(err, domain_errors.ErrInvalidVerificationToken) {
+			return nil, status.Error(codes.InvalidArgument, domain_errors.ErrInvalidVerificationToken.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,111 @@
+package introspection
+
+import (
+	"auth-service/internal/domain/models"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const Path = "/introspect"
+
+type Introspector interface {
+	IntrospectToken(ctx context.Context, token string) (*models.Introspection, error)
+}
+
+// response is the RFC 7662 introspection response. Inactive tokens only
+// report active.
+type response struct {
+	Active    bool     `json:"active"`
+	TokenType string   `json:"token_type,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Sid       string   `json:"sid,omitempty"`
+}
+
+// NewHandler serves RFC 7662 token introspection. Callers authenticate with
+// the API token as a bearer token; an empty API token disables the endpoint.
+func NewHandler(introspector Introspector, apiToken string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST "+Path, func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, apiToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		token := r.PostFormValue("token")
+		if token == "" {
+			http.Error(w, "token is required", http.StatusBadRequest)
+			return
+		}
+
+		introspection, err := introspector.IntrospectToken(r.Context(), token)
+		if err != nil {
+			log.Printf("failed to introspect token: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err = json.NewEncoder(w).Encode(toResponse(introspection)); err != nil {
+			log.Printf("failed to write introspection response: %v", err)
+		}
+	})
+
+	return mux
+}
+
+func authorized(r *http.Request, apiToken string) bool {
+	if apiToken == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) == 1
+}
+
+func toResponse(introspection *models.Introspection) *response {
+	if !introspection.Active {
+		return &response{}
+	}
+
+	result := &response{
+		Active:    true,
+		TokenType: introspection.TokenType,
+		ClientID:  introspection.ClientID,
+		Scope:     introspection.Scope,
+		Jti:       introspection.TokenID,
+		Aud:       introspection.Audience,
+	}
+
+	if introspection.UserID != uuid.Nil {
+		result.Sub = introspection.UserID.String()
+	}
+
+	if !introspection.IssuedAt.IsZero() {
+		result.Iat = introspection.IssuedAt.Unix()
+	}
+
+	if !introspection.ExpiresAt.IsZero() {
+		result.Exp = introspection.ExpiresAt.Unix()
+	}
+
+	if introspection.Session != nil {
+		result.Sid = introspection.Session.ID.String()
+	}
+
+	return result
+}
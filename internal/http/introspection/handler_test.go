@@ -0,0 +1,127 @@
+package introspection
+
+import (
+	"auth-service/internal/domain/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIntrospector map[string]*models.Introspection
+
+func (f fakeIntrospector) IntrospectToken(ctx context.Context, token string) (*models.Introspection, error) {
+	if introspection, ok := f[token]; ok {
+		return introspection, nil
+	}
+
+	return &models.Introspection{}, nil
+}
+
+func introspect(t *testing.T, handler http.Handler, authorization string, token string) *httptest.ResponseRecorder {
+	body := url.Values{"token": {token}}.Encode()
+	req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	var result map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+
+	return result
+}
+
+func TestHandler_MissingServiceToken(t *testing.T) {
+	handler := NewHandler(fakeIntrospector{}, "service-token")
+
+	rec := introspect(t, handler, "", "token")
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+}
+
+func TestHandler_WrongServiceToken(t *testing.T) {
+	handler := NewHandler(fakeIntrospector{}, "service-token")
+
+	rec := introspect(t, handler, "Bearer other-token", "token")
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHandler_Disabled(t *testing.T) {
+	handler := NewHandler(fakeIntrospector{}, "")
+
+	rec := introspect(t, handler, "Bearer ", "token")
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHandler_ExpiredToken(t *testing.T) {
+	handler := NewHandler(fakeIntrospector{}, "service-token")
+
+	rec := introspect(t, handler, "Bearer service-token", "expired")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	require.Equal(t, map[string]any{"active": false}, decode(t, rec))
+}
+
+func TestHandler_ValidToken(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+	issuedAt := time.Unix(1700000000, 0)
+	handler := NewHandler(fakeIntrospector{
+		"valid": {
+			Active:    true,
+			TokenType: "access_token",
+			UserID:    userID,
+			ClientID:  "smap-web",
+			TokenID:   "jti",
+			Audience:  []string{"smap-web"},
+			Scope:     "profile",
+			IssuedAt:  issuedAt,
+			ExpiresAt: issuedAt.Add(15 * time.Minute),
+			Session:   &models.Session{ID: sessionID},
+		},
+	}, "service-token")
+
+	rec := introspect(t, handler, "Bearer service-token", "valid")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, map[string]any{
+		"active":     true,
+		"token_type": "access_token",
+		"sub":        userID.String(),
+		"client_id":  "smap-web",
+		"scope":      "profile",
+		"jti":        "jti",
+		"aud":        []any{"smap-web"},
+		"iat":        float64(issuedAt.Unix()),
+		"exp":        float64(issuedAt.Add(15 * time.Minute).Unix()),
+		"sid":        sessionID.String(),
+	}, decode(t, rec))
+}
+
+func TestHandler_MissingToken(t *testing.T) {
+	handler := NewHandler(fakeIntrospector{}, "service-token")
+
+	rec := introspect(t, handler, "Bearer service-token", "")
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
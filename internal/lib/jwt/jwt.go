@@ -1,10 +1,12 @@
 package jwt
 
 import (
+	domain_errors "auth-service/internal/domain/errors"
 	"auth-service/internal/domain/models"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -13,24 +15,81 @@ import (
 
 type JwtService struct {
 	secret   []byte
+	issuer   string
 	duration time.Duration
+
+	mu         sync.RWMutex
+	staticKeys []*SigningKey
+	keys       []*SigningKey
 }
 
-func NewJwtService(secret []byte, duration time.Duration) *JwtService {
+// NewJwtService creates a token service. New tokens are signed with the most
+// recently activated key of the keyring and stamped with its kid; while the
+// keyring has no active key they are signed with the shared HS256 secret.
+// Tokens without a kid keep validating against the secret as long as one is
+// configured. A non-empty issuer is stamped into new tokens and required
+// from validated ones.
+func NewJwtService(secret []byte, issuer string, duration time.Duration, staticKeys ...*SigningKey) *JwtService {
 	return &JwtService{
-		secret:   secret,
-		duration: duration,
+		secret:     secret,
+		issuer:     issuer,
+		duration:   duration,
+		staticKeys: staticKeys,
 	}
 }
 
-func (j *JwtService) NewToken(user *models.User) (string, time.Duration, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// SetKeys replaces the dynamic part of the keyring. Keys passed to
+// NewJwtService are kept.
+func (j *JwtService) SetKeys(keys []*SigningKey) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.keys = keys
+}
+
+// NewToken issues an access token for the user, intended for the given
+// audience. Every token gets a unique jti so that it can be revoked, and the
+// sid of the session it belongs to.
+func (j *JwtService) NewToken(user *models.User, sessionID uuid.UUID, audience string) (string, time.Duration, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":   user.ID.String(),
 		"uid":   user.ID,
 		"email": user.Email,
-		"exp":   time.Now().Add(j.duration).Unix(),
-	})
+		"jti":   uuid.NewString(),
+		"sid":   sessionID,
+		"iat":   now.Unix(),
+		"nbf":   now.Unix(),
+		"exp":   now.Add(j.duration).Unix(),
+	}
+
+	// Tokens of unverified users are limited, it is up to the services to
+	// decide what they may not be used for
+	claims["email_verified"] = user.EmailVerified()
+
+	if user.Username != "" {
+		claims["username"] = user.Username
+	}
+
+	if j.issuer != "" {
+		claims["iss"] = j.issuer
+	}
 
-	tokenString, err := token.SignedString(j.secret)
+	if audience != "" {
+		claims["aud"] = audience
+	}
+
+	var tokenString string
+	var err error
+
+	if key := j.activeKey(time.Now()); key != nil {
+		token := jwt.NewWithClaims(key.method(), claims)
+		token.Header["kid"] = key.ID
+		tokenString, err = token.SignedString(key.signingMaterial())
+	} else {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		tokenString, err = token.SignedString(j.secret)
+	}
 
 	if err != nil {
 		return "", 0, err
@@ -39,27 +98,180 @@ func (j *JwtService) NewToken(user *models.User) (string, time.Duration, error)
 	return tokenString, j.duration, nil
 }
 
-func (j *JwtService) ValidateToken(tokenString string) uuid.UUID {
+// ValidateToken checks the signature, the time based claims and the issuer
+// of the token and returns its claims. When audience is not empty the token
+// must have been issued for it. The returned error tells why the token was
+// rejected and always matches domain_errors.ErrInvalidToken.
+func (j *JwtService) ValidateToken(tokenString string, audience string) (*models.TokenClaims, error) {
+	options := []jwt.ParserOption{jwt.WithIssuedAt()}
+
+	if j.issuer != "" {
+		options = append(options, jwt.WithIssuer(j.issuer))
+	}
+
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
 
+	claims, err := j.parse(tokenString, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return toTokenClaims(claims)
+}
+
+// ValidateTokenSignature checks the signature and the issuer of the token
+// but not its time based claims, so that the claims of an expired token
+// issued by this service can still be read
+func (j *JwtService) ValidateTokenSignature(tokenString string) (*models.TokenClaims, error) {
+	claims, err := j.parse(tokenString, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, err
+	}
+
+	if issuer, _ := claims.GetIssuer(); j.issuer != "" && issuer != j.issuer {
+		return nil, domain_errors.ErrInvalidToken
+	}
+
+	return toTokenClaims(claims)
+}
+
+// parse verifies the token with the keyring and the given options
+func (j *JwtService) parse(tokenString string, options ...jwt.ParserOption) (jwt.MapClaims, error) {
 	tokenString = strings.TrimSpace(tokenString)
 
-	claims := &jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.secret, nil
-	})
+	options = append(options, jwt.WithValidMethods([]string{
+		AlgorithmHS256,
+		AlgorithmRS256,
+		AlgorithmES256,
+		AlgorithmEdDSA,
+	}))
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, j.verificationKey, options...)
 
 	if err != nil || !token.Valid {
-		log.Println(err)
-		return uuid.Nil
+		return nil, validationError(err)
 	}
-	uid := (*claims)["uid"]
-	id, err := uuid.Parse(uid.(string))
+
+	return claims, nil
+}
+
+// toTokenClaims extracts the claims this service puts into its tokens
+func toTokenClaims(claims jwt.MapClaims) (*models.TokenClaims, error) {
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		subject, _ = claims["uid"].(string)
+	}
+
+	id, err := uuid.Parse(subject)
 	if err != nil {
-		log.Println(err)
-		return uuid.Nil
+		return nil, domain_errors.ErrTokenMalformed
+	}
+
+	result := &models.TokenClaims{
+		UserID: id,
+	}
+	result.Email, _ = claims["email"].(string)
+	result.Username, _ = claims["username"].(string)
+	result.EmailVerified, _ = claims["email_verified"].(bool)
+	result.TokenID, _ = claims["jti"].(string)
+	result.Audience, _ = claims.GetAudience()
+	result.Scope, _ = claims["scope"].(string)
+
+	if sid, ok := claims["sid"].(string); ok {
+		result.SessionID, _ = uuid.Parse(sid)
+	}
+
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		result.IssuedAt = iat.Time
+	}
+
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+
+	return result, nil
+}
+
+// validationError translates a parser error into the reason the token was rejected
+func validationError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return domain_errors.ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return domain_errors.ErrTokenSignatureInvalid
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return domain_errors.ErrTokenAudienceInvalid
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return domain_errors.ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return domain_errors.ErrTokenNotYetValid
+	default:
+		return domain_errors.ErrInvalidToken
+	}
+}
+
+// Jwks returns the public keys that verify tokens issued by this service,
+// including keys that are published ahead of their activation
+func (j *JwtService) Jwks() []models.JSONWebKey {
+	now := time.Now()
+
+	var jwks []models.JSONWebKey
+	for _, key := range j.allKeys() {
+		if key.Secret == nil && !key.isRetiredAt(now) {
+			jwks = append(jwks, key.PublicJwk())
+		}
 	}
-	return id
+
+	return jwks
+}
+
+func (j *JwtService) allKeys() []*SigningKey {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(j.staticKeys)+len(j.keys))
+	keys = append(keys, j.staticKeys...)
+	return append(keys, j.keys...)
+}
+
+// activeKey returns the most recently activated key that is not retired yet
+func (j *JwtService) activeKey(now time.Time) *SigningKey {
+	var active *SigningKey
+
+	for _, key := range j.allKeys() {
+		if key.isActiveAt(now) && (active == nil || key.ActivatesAt.After(active.ActivatesAt)) {
+			active = key
+		}
+	}
+
+	return active
+}
+
+func (j *JwtService) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		if _, isHmac := token.Method.(*jwt.SigningMethodHMAC); isHmac && len(j.secret) > 0 {
+			return j.secret, nil
+		}
+
+		return nil, fmt.Errorf("token has no kid")
+	}
+
+	now := time.Now()
+	for _, key := range j.allKeys() {
+		if key.ID != kid || key.isRetiredAt(now) {
+			continue
+		}
+
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return key.verificationMaterial(), nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key: %v", kid)
 }
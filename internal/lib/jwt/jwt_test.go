@@ -0,0 +1,314 @@
+package jwt
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func pemKey(t *testing.T, key any) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func testKeys(t *testing.T) map[string][]byte {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return map[string][]byte{
+		AlgorithmRS256: pemKey(t, rsaKey),
+		AlgorithmES256: pemKey(t, ecKey),
+		AlgorithmEdDSA: pemKey(t, edKey),
+	}
+}
+
+func activeKey(t *testing.T, algorithm string, activatesAt time.Time) *SigningKey {
+	key, err := GenerateSigningKey(algorithm)
+	require.NoError(t, err)
+
+	key.ActivatesAt = activatesAt
+	return key
+}
+
+func kidOf(t *testing.T, token string) string {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	require.NoError(t, err)
+
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func userOf(claims *models.TokenClaims, _ error) uuid.UUID {
+	if claims == nil {
+		return uuid.Nil
+	}
+
+	return claims.UserID
+}
+
+func TestJwtService_AsymmetricRoundTrip(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "john_doe@test.com"}
+
+	for algorithm, data := range testKeys(t) {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := ParseSigningKey(algorithm, data)
+			require.NoError(t, err)
+			key.ActivatesAt = time.Unix(0, 0)
+
+			service := NewJwtService(nil, "", time.Minute, key)
+			token, _, err := service.NewToken(user, uuid.New(), "")
+			require.NoError(t, err)
+
+			require.Equal(t, user.ID, userOf(service.ValidateToken(token, "")))
+			require.Equal(t, key.ID, kidOf(t, token))
+
+			jwks := service.Jwks()
+			require.Len(t, jwks, 1)
+			require.Equal(t, key.ID, jwks[0].Kid)
+			require.Equal(t, algorithm, jwks[0].Alg)
+		})
+	}
+}
+
+func TestJwtService_AcceptsHS256DuringMigration(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "john_doe@test.com"}
+	secret := []byte("secret")
+
+	legacy := NewJwtService(secret, "", time.Minute)
+	token, _, err := legacy.NewToken(user, uuid.New(), "")
+	require.NoError(t, err)
+	require.Empty(t, kidOf(t, token))
+
+	key := activeKey(t, AlgorithmEdDSA, time.Unix(0, 0))
+
+	require.Equal(t, user.ID, userOf(NewJwtService(secret, "", time.Minute, key).ValidateToken(token, "")))
+	require.Equal(t, uuid.Nil, userOf(NewJwtService(nil, "", time.Minute, key).ValidateToken(token, "")))
+}
+
+func TestJwtService_RejectsForeignKey(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "john_doe@test.com"}
+
+	issuerKey := activeKey(t, AlgorithmES256, time.Unix(0, 0))
+	otherKey := activeKey(t, AlgorithmES256, time.Unix(0, 0))
+
+	token, _, err := NewJwtService(nil, "", time.Minute, issuerKey).NewToken(user, uuid.New(), "")
+	require.NoError(t, err)
+
+	require.Equal(t, uuid.Nil, userOf(NewJwtService(nil, "", time.Minute, otherKey).ValidateToken(token, "")))
+}
+
+func TestJwtService_KeyRotation(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "john_doe@test.com"}
+	now := time.Now()
+
+	oldKey := activeKey(t, AlgorithmHS256, now.Add(-time.Hour))
+	service := NewJwtService(nil, "", time.Minute)
+	service.SetKeys([]*SigningKey{oldKey})
+
+	oldToken, _, err := service.NewToken(user, uuid.New(), "")
+	require.NoError(t, err)
+	require.Equal(t, oldKey.ID, kidOf(t, oldToken))
+
+	// A published key is not used for signing until it is promoted
+	newKey, err := GenerateSigningKey(AlgorithmRS256)
+	require.NoError(t, err)
+	service.SetKeys([]*SigningKey{oldKey, newKey})
+
+	token, _, err := service.NewToken(user, uuid.New(), "")
+	require.NoError(t, err)
+	require.Equal(t, oldKey.ID, kidOf(t, token))
+	require.Len(t, service.Jwks(), 1)
+
+	newKey.ActivatesAt = now.Add(-time.Second)
+	oldKey.RetiresAt = now.Add(time.Minute)
+
+	token, _, err = service.NewToken(user, uuid.New(), "")
+	require.NoError(t, err)
+	require.Equal(t, newKey.ID, kidOf(t, token))
+	require.Equal(t, user.ID, userOf(service.ValidateToken(token, "")))
+	require.Equal(t, user.ID, userOf(service.ValidateToken(oldToken, "")))
+
+	oldKey.RetiresAt = now.Add(-time.Second)
+	require.Equal(t, uuid.Nil, userOf(service.ValidateToken(oldToken, "")))
+}
+
+func TestJwtService_Claims(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "john_doe@test.com", Username: "JDoe", VerifiedAt: time.Now()}
+	sessionID := uuid.New()
+	service := NewJwtService([]byte("secret"), "", time.Minute)
+
+	first, _, err := service.NewToken(user, sessionID, "")
+	require.NoError(t, err)
+
+	second, _, err := service.NewToken(user, sessionID, "")
+	require.NoError(t, err)
+
+	claims, err := service.ValidateToken(first, "")
+	require.NoError(t, err)
+	require.Equal(t, user.ID, claims.UserID)
+	require.Equal(t, user.Email, claims.Email)
+	require.Equal(t, user.Username, claims.Username)
+	require.True(t, claims.EmailVerified)
+	require.Equal(t, sessionID, claims.SessionID)
+	require.NotEmpty(t, claims.TokenID)
+	require.WithinDuration(t, time.Now().Add(time.Minute), claims.ExpiresAt, 2*time.Second)
+	secondClaims, err := service.ValidateToken(second, "")
+	require.NoError(t, err)
+	require.NotEqual(t, claims.TokenID, secondClaims.TokenID)
+}
+
+func TestJwtService_ScopeClaim(t *testing.T) {
+	secret := []byte("secret")
+	service := NewJwtService(secret, "", time.Minute)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   uuid.NewString(),
+		"scope": "profile friends:read",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secret)
+	require.NoError(t, err)
+
+	claims, err := service.ValidateToken(token, "")
+	require.NoError(t, err)
+	require.Equal(t, "profile friends:read", claims.Scope)
+}
+
+func TestJwtService_IssuerAndAudience(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "john_doe@test.com"}
+	service := NewJwtService([]byte("secret"), "smap-auth", time.Minute)
+
+	token, _, err := service.NewToken(user, uuid.New(), "smap-web")
+	require.NoError(t, err)
+
+	claims, err := service.ValidateToken(token, "smap-web")
+	require.NoError(t, err)
+	require.Equal(t, []string{"smap-web"}, claims.Audience)
+	require.WithinDuration(t, time.Now(), claims.IssuedAt, 2*time.Second)
+
+	_, err = service.ValidateToken(token, "")
+	require.NoError(t, err)
+
+	_, err = service.ValidateToken(token, "smap-mobile")
+	require.ErrorIs(t, err, domain_errors.ErrTokenAudienceInvalid)
+
+	_, err = NewJwtService([]byte("secret"), "other-issuer", time.Minute).ValidateToken(token, "")
+	require.ErrorIs(t, err, domain_errors.ErrInvalidToken)
+}
+
+func TestJwtService_ValidationErrors(t *testing.T) {
+	secret := []byte("secret")
+	service := NewJwtService(secret, "", time.Minute)
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		require.NoError(t, err)
+		return token
+	}
+
+	now := time.Now()
+	subject := uuid.NewString()
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"expired", sign(jwt.MapClaims{"sub": subject, "exp": now.Add(-time.Minute).Unix()}), domain_errors.ErrTokenExpired},
+		{"not yet valid", sign(jwt.MapClaims{"sub": subject, "nbf": now.Add(time.Hour).Unix()}), domain_errors.ErrTokenNotYetValid},
+		{"malformed", "not-a-token", domain_errors.ErrTokenMalformed},
+		{"no subject", sign(jwt.MapClaims{"uid": 42}), domain_errors.ErrTokenMalformed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := service.ValidateToken(test.token, "")
+			require.Nil(t, claims)
+			require.ErrorIs(t, err, test.err)
+			require.ErrorIs(t, err, domain_errors.ErrInvalidToken)
+		})
+	}
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": subject}).SignedString([]byte("other"))
+	require.NoError(t, err)
+
+	_, err = service.ValidateToken(forged, "")
+	require.ErrorIs(t, err, domain_errors.ErrTokenSignatureInvalid)
+}
+
+func TestJwtService_ValidateTokenSignature(t *testing.T) {
+	secret := []byte("secret")
+	service := NewJwtService(secret, "smap-auth", time.Minute)
+
+	sign := func(claims jwt.MapClaims, key []byte) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+
+	subject := uuid.NewString()
+	sid := uuid.New()
+	expired := sign(jwt.MapClaims{
+		"sub": subject,
+		"sid": sid.String(),
+		"jti": "jti",
+		"iss": "smap-auth",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}, secret)
+
+	_, err := service.ValidateToken(expired, "")
+	require.ErrorIs(t, err, domain_errors.ErrTokenExpired)
+
+	claims, err := service.ValidateTokenSignature(expired)
+	require.NoError(t, err)
+	require.Equal(t, sid, claims.SessionID)
+	require.Equal(t, "jti", claims.TokenID)
+
+	_, err = service.ValidateTokenSignature(sign(jwt.MapClaims{"sub": subject, "iss": "smap-auth"}, []byte("other")))
+	require.ErrorIs(t, err, domain_errors.ErrTokenSignatureInvalid)
+
+	_, err = service.ValidateTokenSignature(sign(jwt.MapClaims{"sub": subject, "iss": "other-issuer"}, secret))
+	require.ErrorIs(t, err, domain_errors.ErrInvalidToken)
+}
+
+func TestSigningKey_EncodeRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmHS256, AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		key, err := GenerateSigningKey(algorithm)
+		require.NoError(t, err)
+
+		data, err := EncodeSigningKey(key)
+		require.NoError(t, err)
+
+		parsed, err := ParseSigningKey(algorithm, data)
+		require.NoError(t, err)
+		require.Equal(t, key.Secret, parsed.Secret)
+
+		if key.Secret == nil {
+			require.Equal(t, key.ID, parsed.ID)
+		}
+	}
+}
+
+func TestParseSigningKey_AlgorithmMismatch(t *testing.T) {
+	_, err := ParseSigningKey(AlgorithmES256, testKeys(t)[AlgorithmRS256])
+	require.Error(t, err)
+}
@@ -0,0 +1,277 @@
+package jwt
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const (
+	secretKeyBlockType = "SECRET KEY"
+	secretKeyBytes     = 32
+	rsaKeyBits         = 2048
+)
+
+// SigningKey is a keyring entry: either an asymmetric private key or an
+// HMAC secret, together with the JWS algorithm it signs with.
+// A zero ActivatesAt means the key is published but not used for signing,
+// a zero RetiresAt means the key never stops verifying.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  crypto.Signer
+	Secret      []byte
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+}
+
+// GenerateSigningKey creates a fresh key for the given algorithm
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+
+	switch algorithm {
+	case AlgorithmHS256:
+		secret := make([]byte, secretKeyBytes)
+		if _, err = rand.Read(secret); err != nil {
+			return nil, err
+		}
+
+		id := make([]byte, 16)
+		if _, err = rand.Read(id); err != nil {
+			return nil, err
+		}
+
+		return &SigningKey{
+			ID:        encodeSegment(id),
+			Algorithm: algorithm,
+			Secret:    secret,
+		}, nil
+	case AlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, domain_errors.ErrUnsupportedAlgorithm
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not generate signing key: %w", err)
+	}
+
+	return newAsymmetricKey(algorithm, signer)
+}
+
+// LoadSigningKey reads a PEM encoded private key from disk
+func LoadSigningKey(algorithm string, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read signing key: %w", err)
+	}
+
+	return ParseSigningKey(algorithm, data)
+}
+
+// ParseSigningKey decodes a PKCS#8, PKCS#1 or SEC 1 private key, or an HMAC
+// secret written by EncodeSigningKey, and checks that it can be used with
+// the requested algorithm
+func ParseSigningKey(algorithm string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key is not PEM encoded")
+	}
+
+	if block.Type == secretKeyBlockType {
+		if algorithm != AlgorithmHS256 {
+			return nil, fmt.Errorf("secret key cannot be used with %s", algorithm)
+		}
+
+		return &SigningKey{
+			Algorithm: algorithm,
+			Secret:    block.Bytes,
+		}, nil
+	}
+
+	var key any
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signing key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			return nil, fmt.Errorf("RSA key cannot be used with %s", algorithm)
+		}
+	case *ecdsa.PrivateKey:
+		if algorithm != AlgorithmES256 || k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ECDSA key cannot be used with %s", algorithm)
+		}
+	case ed25519.PrivateKey:
+		if algorithm != AlgorithmEdDSA {
+			return nil, fmt.Errorf("Ed25519 key cannot be used with %s", algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+
+	return newAsymmetricKey(algorithm, key.(crypto.Signer))
+}
+
+// EncodeSigningKey serializes the key material so that ParseSigningKey can read it back
+func EncodeSigningKey(key *SigningKey) ([]byte, error) {
+	if key.Secret != nil {
+		return pem.EncodeToMemory(&pem.Block{Type: secretKeyBlockType, Bytes: key.Secret}), nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode signing key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func newAsymmetricKey(algorithm string, signer crypto.Signer) (*SigningKey, error) {
+	signingKey := &SigningKey{
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+	}
+
+	var err error
+	signingKey.ID, err = thumbprint(signingKey.PublicJwk())
+	if err != nil {
+		return nil, err
+	}
+
+	return signingKey, nil
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+func (k *SigningKey) signingMaterial() any {
+	if k.Secret != nil {
+		return k.Secret
+	}
+
+	return k.PrivateKey
+}
+
+func (k *SigningKey) verificationMaterial() any {
+	if k.Secret != nil {
+		return k.Secret
+	}
+
+	return k.PrivateKey.Public()
+}
+
+func (k *SigningKey) isActiveAt(now time.Time) bool {
+	return !k.ActivatesAt.IsZero() && !k.ActivatesAt.After(now) && !k.isRetiredAt(now)
+}
+
+func (k *SigningKey) isRetiredAt(now time.Time) bool {
+	return !k.RetiresAt.IsZero() && !k.RetiresAt.After(now)
+}
+
+// PublicJwk describes the public half of the key as a JSON Web Key
+func (k *SigningKey) PublicJwk() models.JSONWebKey {
+	jwk := models.JSONWebKey{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Algorithm,
+	}
+
+	switch pub := k.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(pub.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeSegment(pub.X.FillBytes(make([]byte, 32)))
+		jwk.Y = encodeSegment(pub.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(pub)
+	}
+
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 thumbprint used as the key id
+func thumbprint(jwk models.JSONWebKey) (string, error) {
+	var members any
+
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return encodeSegment(sum[:]), nil
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
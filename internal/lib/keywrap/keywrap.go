@@ -0,0 +1,90 @@
+package keywrap
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeyLength is the length of the key-encryption key, which selects AES-256
+const KeyLength = 32
+
+// prefix marks wrapped keys and the format version
+var prefix = []byte("aes256gcm:v1:")
+
+var (
+	ErrNotWrapped  = errors.New("key material is not wrapped")
+	ErrUnwrapFails = errors.New("key material can not be unwrapped")
+)
+
+// Wrapper encrypts key material with a key-encryption key, so that the
+// stored form is useless without it. The associated data, such as the key
+// id, binds the ciphertext to its row.
+type Wrapper struct {
+	aead cipher.AEAD
+}
+
+func New(kek []byte) (*Wrapper, error) {
+	if len(kek) != KeyLength {
+		return nil, fmt.Errorf("key-encryption key must be %d bytes, got %d", KeyLength, len(kek))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wrapper{aead: aead}, nil
+}
+
+// Wrap returns the encrypted key material in a printable form
+func (w *Wrapper) Wrap(plaintext []byte, associated string) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := w.aead.Seal(nonce, nonce, plaintext, []byte(associated))
+
+	wrapped := make([]byte, len(prefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(wrapped, prefix)
+	base64.StdEncoding.Encode(wrapped[len(prefix):], sealed)
+
+	return wrapped, nil
+}
+
+// Unwrap decrypts key material made by Wrap with the same associated data.
+// It fails with ErrNotWrapped for material stored in the clear.
+func (w *Wrapper) Unwrap(wrapped []byte, associated string) ([]byte, error) {
+	if !IsWrapped(wrapped) {
+		return nil, ErrNotWrapped
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(wrapped[len(prefix):]))
+	if err != nil || len(sealed) < w.aead.NonceSize() {
+		return nil, ErrUnwrapFails
+	}
+
+	nonce, ciphertext := sealed[:w.aead.NonceSize()], sealed[w.aead.NonceSize():]
+
+	plaintext, err := w.aead.Open(nil, nonce, ciphertext, []byte(associated))
+	if err != nil {
+		return nil, ErrUnwrapFails
+	}
+
+	return plaintext, nil
+}
+
+// IsWrapped reports whether the material was made by Wrap
+func IsWrapped(material []byte) bool {
+	return bytes.HasPrefix(material, prefix)
+}
@@ -0,0 +1,165 @@
+package passhash
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrUnknownFormat = errors.New("unknown password hash format")
+
+// Argon2Params are the cost parameters of argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes made by any supported one. Argon2id hashes are stored in the PHC
+// string format. Bcrypt hashes keep their modular crypt format, which the
+// PHC format is compatible with.
+type Hasher struct {
+	algorithm  string
+	argon2     Argon2Params
+	bcryptCost int
+}
+
+func New(algorithm string, argon2Params Argon2Params, bcryptCost int) (*Hasher, error) {
+	switch algorithm {
+	case AlgorithmArgon2id:
+		if argon2Params.Memory == 0 || argon2Params.Iterations == 0 || argon2Params.Parallelism == 0 {
+			return nil, fmt.Errorf("invalid argon2id parameters: %+v", argon2Params)
+		}
+	case AlgorithmBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost: %d", bcryptCost)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", algorithm)
+	}
+
+	return &Hasher{
+		algorithm:  algorithm,
+		argon2:     argon2Params,
+		bcryptCost: bcryptCost,
+	}, nil
+}
+
+// Hash returns the encoded hash of the password
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return nil, domain_errors.ErrPasswordTooLong
+		}
+		return hash, err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.argon2.Iterations, h.argon2.Memory, h.argon2.Parallelism, argon2KeyLength)
+
+	return []byte(fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.argon2.Memory,
+		h.argon2.Iterations,
+		h.argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+// Verify reports whether the password matches the hash, and whether the hash
+// should be replaced because it was made with another algorithm or other
+// parameters than the configured ones
+func (h *Hasher) Verify(password string, hash []byte) (match bool, needsRehash bool, err error) {
+	encoded := string(hash)
+
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		return h.verifyArgon2id(password, encoded)
+	}
+
+	if strings.HasPrefix(encoded, "$2") {
+		return h.verifyBcrypt(password, hash)
+	}
+
+	return false, false, ErrUnknownFormat
+}
+
+func (h *Hasher) verifyArgon2id(password string, encoded string) (bool, bool, error) {
+	var version int
+	var params Argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnknownFormat
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrUnknownFormat
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return false, false, ErrUnknownFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnknownFormat
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrUnknownFormat
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return false, false, nil
+	}
+
+	return true, h.algorithm != AlgorithmArgon2id || params != h.argon2 || len(key) != argon2KeyLength, nil
+}
+
+func (h *Hasher) verifyBcrypt(password string, hash []byte) (bool, bool, error) {
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	if h.algorithm != AlgorithmBcrypt {
+		return true, true, nil
+	}
+
+	cost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return false, false, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	return true, cost != h.bcryptCost, nil
+}
@@ -0,0 +1,107 @@
+package passhash
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func newHasher(t *testing.T, algorithm string, argon2Params Argon2Params, bcryptCost int) *Hasher {
+	hasher, err := New(algorithm, argon2Params, bcryptCost)
+	require.NoError(t, err)
+
+	return hasher
+}
+
+func TestHasher_Argon2idRoundTrip(t *testing.T) {
+	hasher := newHasher(t, AlgorithmArgon2id, testArgon2Params, bcrypt.MinCost)
+
+	hash, err := hasher.Hash("password")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(hash), "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	match, needsRehash, err := hasher.Verify("password", hash)
+	require.NoError(t, err)
+	require.True(t, match)
+	require.False(t, needsRehash)
+
+	match, _, err = hasher.Verify("wrong password", hash)
+	require.NoError(t, err)
+	require.False(t, match)
+}
+
+func TestHasher_Argon2idKeepsLongPasswords(t *testing.T) {
+	hasher := newHasher(t, AlgorithmArgon2id, testArgon2Params, bcrypt.MinCost)
+	password := strings.Repeat("a", 80)
+
+	hash, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	match, _, err := hasher.Verify(password[:72], hash)
+	require.NoError(t, err)
+	require.False(t, match)
+}
+
+func TestHasher_BcryptRejectsLongPasswords(t *testing.T) {
+	hasher := newHasher(t, AlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost)
+
+	_, err := hasher.Hash(strings.Repeat("a", 80))
+	require.ErrorIs(t, err, domain_errors.ErrPasswordTooLong)
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	bcryptHash, err := newHasher(t, AlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost).Hash("password")
+	require.NoError(t, err)
+
+	argon2Hash, err := newHasher(t, AlgorithmArgon2id, testArgon2Params, bcrypt.MinCost).Hash("password")
+	require.NoError(t, err)
+
+	stronger := Argon2Params{Memory: 128, Iterations: 1, Parallelism: 1}
+
+	cases := []struct {
+		name   string
+		hasher *Hasher
+		hash   []byte
+		rehash bool
+	}{
+		{"bcrypt to argon2id", newHasher(t, AlgorithmArgon2id, testArgon2Params, bcrypt.MinCost), bcryptHash, true},
+		{"bcrypt cost raised", newHasher(t, AlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost+1), bcryptHash, true},
+		{"bcrypt unchanged", newHasher(t, AlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost), bcryptHash, false},
+		{"argon2id to bcrypt", newHasher(t, AlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost), argon2Hash, true},
+		{"argon2id parameters raised", newHasher(t, AlgorithmArgon2id, stronger, bcrypt.MinCost), argon2Hash, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			match, needsRehash, err := c.hasher.Verify("password", c.hash)
+			require.NoError(t, err)
+			require.True(t, match)
+			require.Equal(t, c.rehash, needsRehash)
+		})
+	}
+}
+
+func TestHasher_UnknownFormat(t *testing.T) {
+	hasher := newHasher(t, AlgorithmArgon2id, testArgon2Params, bcrypt.MinCost)
+
+	for _, hash := range []string{"", "plain", "$argon2id$v=19$m=64$salt$key", "$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		_, _, err := hasher.Verify("password", []byte(hash))
+		require.ErrorIs(t, err, ErrUnknownFormat, hash)
+	}
+}
+
+func TestNew_InvalidSettings(t *testing.T) {
+	_, err := New("md5", testArgon2Params, bcrypt.MinCost)
+	require.Error(t, err)
+
+	_, err = New(AlgorithmArgon2id, Argon2Params{}, bcrypt.MinCost)
+	require.Error(t, err)
+
+	_, err = New(AlgorithmBcrypt, testArgon2Params, bcrypt.MaxCost+1)
+	require.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package pepper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// VersionNone marks hashes made from the bare password
+const VersionNone = 0
+
+var ErrUnknownVersion = errors.New("unknown pepper version")
+
+// Peppers keeps the server side secrets mixed into passwords before they are
+// hashed. Every stored hash records the version of the pepper it was made
+// with, so that old versions keep verifying while users are moved to the
+// current one.
+type Peppers struct {
+	current int
+	secrets map[int][]byte
+}
+
+// New returns the peppers with the given secrets by version. A current
+// version of VersionNone leaves new passwords unpeppered.
+func New(secrets map[int][]byte, current int) (*Peppers, error) {
+	for version, secret := range secrets {
+		if version <= VersionNone {
+			return nil, fmt.Errorf("invalid pepper version: %d", version)
+		}
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("empty pepper for version %d", version)
+		}
+	}
+
+	if _, ok := secrets[current]; !ok && current != VersionNone {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, current)
+	}
+
+	return &Peppers{
+		current: current,
+		secrets: secrets,
+	}, nil
+}
+
+// Current returns the version new passwords are peppered with
+func (p *Peppers) Current() int {
+	return p.current
+}
+
+// Apply mixes the pepper of the version into the password. The result is the
+// base64 encoded HMAC-SHA256 of the password, which also keeps it within the
+// input limit of bcrypt.
+func (p *Peppers) Apply(password string, version int) (string, error) {
+	if version == VersionNone {
+		return password, nil
+	}
+
+	secret, ok := p.secrets[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
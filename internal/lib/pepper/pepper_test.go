@@ -0,0 +1,46 @@
+package pepper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeppers_Apply(t *testing.T) {
+	peppers, err := New(map[int][]byte{1: []byte("old secret"), 2: []byte("new secret")}, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, peppers.Current())
+
+	bare, err := peppers.Apply("password", VersionNone)
+	require.NoError(t, err)
+	require.Equal(t, "password", bare)
+
+	first, err := peppers.Apply("password", 1)
+	require.NoError(t, err)
+	second, err := peppers.Apply("password", 2)
+	require.NoError(t, err)
+	require.NotEqual(t, "password", first)
+	require.NotEqual(t, first, second)
+
+	again, err := peppers.Apply("password", 1)
+	require.NoError(t, err)
+	require.Equal(t, first, again)
+
+	_, err = peppers.Apply("password", 3)
+	require.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(map[int][]byte{1: []byte("secret")}, 2)
+	require.ErrorIs(t, err, ErrUnknownVersion)
+
+	_, err = New(map[int][]byte{0: []byte("secret")}, 0)
+	require.Error(t, err)
+
+	_, err = New(map[int][]byte{1: nil}, 1)
+	require.Error(t, err)
+
+	peppers, err := New(nil, VersionNone)
+	require.NoError(t, err)
+	require.Equal(t, VersionNone, peppers.Current())
+}
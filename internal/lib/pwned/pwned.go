@@ -0,0 +1,182 @@
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxFileSize bounds a dataset given as a single file, which is held in
+// memory. The full dataset is tens of gigabytes and has to be given as a
+// range directory, which is searched on disk.
+const MaxFileSize = 64 << 20
+
+// Index is a set of the SHA-1 hashes of breached passwords. It either reads
+// the range file of a hash from disk on every lookup, or holds the hashes of
+// a small dataset in memory.
+type Index struct {
+	dir      string
+	minCount int
+
+	hashes [][sha1.Size]byte
+}
+
+// prefixLen is the length of the hash prefix a range is keyed by
+const prefixLen = 5
+
+// Open opens a Have I Been Pwned dataset in range format. Every line is a
+// hash suffix, a colon and the number of breaches the password was seen in.
+// The path is either a directory holding one file per range, named by its 5
+// character hash prefix and a .txt extension as the official downloader
+// writes them, or a single file of at most MaxFileSize. A file not named by a
+// prefix must carry the full hash on every line, as the downloader does in
+// single file mode. Hashes seen fewer than minCount times are left out.
+func Open(path string, minCount int) (*Index, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &Index{dir: path, minCount: minCount}, nil
+	}
+
+	if info.Size() > MaxFileSize {
+		return nil, fmt.Errorf("%s: %d bytes is over the limit of %d, use a range directory", path, info.Size(), MaxFileSize)
+	}
+
+	index := &Index{minCount: minCount}
+	if err = index.loadFile(path, rangePrefix(path)); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(index.hashes, func(a, b [sha1.Size]byte) int {
+		return bytes.Compare(a[:], b[:])
+	})
+
+	return index, nil
+}
+
+// loadFile adds the hashes of a file to memory. Lines only carry the suffix
+// when the prefix is known.
+func (i *Index) loadFile(path string, prefix string) error {
+	return i.scanFile(path, prefix, func(hash [sha1.Size]byte) bool {
+		i.hashes = append(i.hashes, hash)
+		return false
+	})
+}
+
+// scanFile calls found with every hash of the file seen at least minCount
+// times, until it returns true
+func (i *Index) scanFile(path string, prefix string, found func(hash [sha1.Size]byte) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		hash, count, err := parseLine(prefix, text)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		if count >= i.minCount && found(hash) {
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
+
+// rangePrefix returns the hash prefix the file is named by, or an empty
+// string when its name is not one
+func rangePrefix(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	if len(name) != prefixLen {
+		return ""
+	}
+
+	if _, err := hex.DecodeString(name + "0"); err != nil {
+		return ""
+	}
+
+	return strings.ToUpper(name)
+}
+
+func parseLine(prefix string, text string) ([sha1.Size]byte, int, error) {
+	var hash [sha1.Size]byte
+
+	hexHash, countText, ok := strings.Cut(text, ":")
+	if !ok {
+		return hash, 0, fmt.Errorf("expected SUFFIX:COUNT, got %q", text)
+	}
+
+	if prefix != "" && len(hexHash) == hex.EncodedLen(sha1.Size)-prefixLen {
+		hexHash = prefix + hexHash
+	}
+
+	if len(hexHash) != hex.EncodedLen(sha1.Size) {
+		return hash, 0, fmt.Errorf("expected SUFFIX:COUNT, got %q", text)
+	}
+
+	if _, err := hex.Decode(hash[:], []byte(hexHash)); err != nil {
+		return hash, 0, fmt.Errorf("invalid hash: %w", err)
+	}
+
+	count, err := strconv.Atoi(countText)
+	if err != nil {
+		return hash, 0, fmt.Errorf("invalid count: %w", err)
+	}
+
+	return hash, count, nil
+}
+
+// Contains reports whether the password is in the index. Looking it up in a
+// range directory reads the range file of its hash, a missing file holds no
+// hashes.
+func (i *Index) Contains(password string) (bool, error) {
+	hash := sha1.Sum([]byte(password))
+
+	if i.dir == "" {
+		_, found := slices.BinarySearchFunc(i.hashes, hash, func(a, b [sha1.Size]byte) int {
+			return bytes.Compare(a[:], b[:])
+		})
+
+		return found, nil
+	}
+
+	prefix := strings.ToUpper(hex.EncodeToString(hash[:]))[:prefixLen]
+
+	var found bool
+	err := i.scanFile(filepath.Join(i.dir, prefix+".txt"), prefix, func(candidate [sha1.Size]byte) bool {
+		found = candidate == hash
+		return found
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,127 @@
+package pwned
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func hashOf(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func writeDataset(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), "pwned-passwords.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600))
+
+	return path
+}
+
+// contains looks the password up and fails the test on an error
+func contains(t *testing.T, index *Index, password string) bool {
+	found, err := index.Contains(password)
+	require.NoError(t, err)
+
+	return found
+}
+
+func TestIndex_Contains(t *testing.T) {
+	path := writeDataset(t,
+		hashOf("password")+":9545824",
+		hashOf("rarely used")+":2",
+		"",
+		hashOf("123456")+":37359195",
+	)
+
+	index, err := Open(path, 1)
+	require.NoError(t, err)
+	require.Len(t, index.hashes, 3)
+
+	require.True(t, contains(t, index, "password"))
+	require.True(t, contains(t, index, "123456"))
+	require.True(t, contains(t, index, "rarely used"))
+	require.False(t, contains(t, index, "correct horse battery staple"))
+}
+
+func TestIndex_MinCount(t *testing.T) {
+	path := writeDataset(t,
+		hashOf("password")+":9545824",
+		hashOf("rarely used")+":2",
+	)
+
+	index, err := Open(path, 10)
+	require.NoError(t, err)
+	require.Len(t, index.hashes, 1)
+
+	require.True(t, contains(t, index, "password"))
+	require.False(t, contains(t, index, "rarely used"))
+}
+
+func TestOpen_Malformed(t *testing.T) {
+	for _, line := range []string{
+		hashOf("password"),
+		hashOf("password")[:39] + ":1",
+		strings.Repeat("Z", 40) + ":1",
+		hashOf("password")[5:] + ":1",
+		hashOf("password") + ":many",
+	} {
+		_, err := Open(writeDataset(t, line), 1)
+		require.Error(t, err, line)
+	}
+}
+
+func TestOpen_FileTooLarge(t *testing.T) {
+	path := writeDataset(t, hashOf("password")+":9545824")
+	require.NoError(t, os.Truncate(path, MaxFileSize+1))
+
+	_, err := Open(path, 1)
+	require.ErrorContains(t, err, "range directory")
+}
+
+func TestOpen_RangeDirectory(t *testing.T) {
+	index, err := Open(filepath.Join("testdata", "ranges"), 1)
+	require.NoError(t, err)
+	require.Empty(t, index.hashes)
+
+	require.True(t, contains(t, index, "password"))
+	require.True(t, contains(t, index, "123456"))
+	require.True(t, contains(t, index, "rarely used"))
+	require.False(t, contains(t, index, "correct horse battery staple"))
+
+	index, err = Open(filepath.Join("testdata", "ranges"), 10)
+	require.NoError(t, err)
+	require.True(t, contains(t, index, "password"))
+	require.False(t, contains(t, index, "rarely used"))
+}
+
+func TestOpen_RangeFile(t *testing.T) {
+	index, err := Open(filepath.Join("testdata", "ranges", hashOf("password")[:5]+".txt"), 1)
+	require.NoError(t, err)
+	require.Len(t, index.hashes, 4)
+
+	require.True(t, contains(t, index, "password"))
+	require.False(t, contains(t, index, "123456"))
+}
+
+func TestIndex_Contains_MalformedRange(t *testing.T) {
+	dir := t.TempDir()
+	prefix := hashOf("password")[:5]
+	require.NoError(t, os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte("not a range\r\n"), 0o600))
+
+	index, err := Open(dir, 1)
+	require.NoError(t, err)
+
+	_, err = index.Contains("password")
+	require.Error(t, err)
+}
+
+func TestOpen_Missing(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing"), 1)
+	require.Error(t, err)
+}
@@ -4,52 +4,151 @@ import (
 	"auth-service/internal/config"
 	domain_errors "auth-service/internal/domain/errors"
 	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
 	userSaver    UserSaver
 	userProvider UserProvider
 	jwtService   TokenProvider
+	hasher       PasswordHasher
+	peppers      PasswordPeppers
+	breaches     BreachedPasswords
 	config       *config.Config
 	redis        Cache
 	kafka        MessageBroker
+	stop         chan struct{}
+
+	// background tracks the work that runs after a request returns
+	background sync.WaitGroup
+
+	// dummyPassHash is compared against when the account does not exist. It
+	// is made on first use by the configured hasher, so that checking it
+	// costs the same as checking a fresh hash of a real account.
+	dummyPassHash func() []byte
 }
 
 type UserSaver interface {
 	SaveUser(
 		ctx context.Context,
 		email string,
+		username string,
 		passHash []byte,
+		pepperVersion int,
 	) (uuid.UUID, error)
+	SetEmailVerified(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passHash []byte, pepperVersion int) error
+	SetPassword(
+		ctx context.Context,
+		id uuid.UUID,
+		passHash []byte,
+		pepperVersion int,
+		historySize int,
+		retiredAfter time.Time,
+	) error
+	SetPendingEmail(ctx context.Context, id uuid.UUID, email string) error
+	ConfirmEmailChange(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) (string, error)
+	RestoreEmail(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error
+	DeleteUser(ctx context.Context, id uuid.UUID, deletedAt time.Time) error
+	RestoreUser(ctx context.Context, id uuid.UUID) error
+	SuspendUser(ctx context.Context, id uuid.UUID, reason string, until time.Time) error
+	UnsuspendUser(ctx context.Context, id uuid.UUID) error
+	RecordFailedLogin(
+		ctx context.Context,
+		id uuid.UUID,
+		failedAt time.Time,
+		windowStart time.Time,
+		maxFailures int,
+		lockedUntil time.Time,
+	) (bool, error)
+	ResetFailedLogins(ctx context.Context, id uuid.UUID) error
+	UnlockUser(ctx context.Context, id uuid.UUID) error
+	PurgeDeletedUsers(
+		ctx context.Context,
+		deletedBefore time.Time,
+		limit int,
+		announce func(users []*models.User) error,
+	) (int, error)
 }
 
 type UserProvider interface {
 	GetUser(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error)
+	GetPasswordHistory(ctx context.Context, userID uuid.UUID, limit int, retiredAfter time.Time) ([]models.PasswordHash, error)
+	GetDeletedUser(ctx context.Context, identifier string) (*models.User, error)
 }
 
 type Cache interface {
-	StoreToken(key string, value uuid.UUID, ttl time.Duration)
-	RemoveToken(key string) error
+	StoreRefreshToken(key string, token *models.RefreshToken, ttl time.Duration) error
+	GetRefreshToken(key string) (*models.RefreshToken, error)
+	MarkRefreshTokenRotated(key string) (bool, error)
+	RevokeToken(key string, ttl time.Duration) error
+	IsTokenRevoked(key string) (bool, error)
+	CreateSession(session *models.Session, ttl time.Duration) error
+	GetSession(id uuid.UUID) (*models.Session, error)
+	TouchSession(session *models.Session, ttl time.Duration) error
+	ListSessions(userID uuid.UUID) ([]*models.Session, error)
+	RemoveSession(userID uuid.UUID, id uuid.UUID) error
+	RemoveUserSessions(userID uuid.UUID, except ...uuid.UUID) error
+	StoreOneTimeToken(key string, token *models.OneTimeToken, ttl time.Duration) error
+	GetOneTimeToken(key string) (*models.OneTimeToken, error)
+	ConsumeOneTimeToken(key string) (*models.OneTimeToken, error)
+	ReserveLoginAttempt(
+		id string,
+		windows []models.LoginWindow,
+		at time.Time,
+		period time.Duration,
+		backoff models.LoginBackoff,
+	) (time.Duration, error)
+	ReleaseLoginAttempt(id string, keys []string) error
+	ResetLoginFailures(key string) error
 }
 
 type TokenProvider interface {
-	NewToken(user *models.User) (string, time.Duration, error)
-	ValidateToken(tokenString string) uuid.UUID
+	NewToken(user *models.User, sessionID uuid.UUID, audience string) (string, time.Duration, error)
+	ValidateToken(tokenString string, audience string) (*models.TokenClaims, error)
+	ValidateTokenSignature(tokenString string) (*models.TokenClaims, error)
+	Jwks() []models.JSONWebKey
+}
+
+// PasswordHasher hashes passwords. Verify also reports whether the hash was
+// made with outdated settings and should be replaced.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	Verify(password string, hash []byte) (match bool, needsRehash bool, err error)
+}
+
+// PasswordPeppers mixes a server side secret into passwords before they are
+// hashed. Version 0 leaves the password as it is.
+type PasswordPeppers interface {
+	Current() int
+	Apply(password string, version int) (string, error)
+}
+
+// BreachedPasswords tells whether a password is known from data breaches
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
 }
 
 type MessageBroker interface {
 	Produce(msg kafka.Message) error
 }
 
+const TopicUserCreated = "user-created"
+
 type UserCreatedEvent struct {
 	UserID    uuid.UUID `json:"user_id"`
 	Username  string    `json:"username"`
@@ -61,44 +160,262 @@ func New(
 	userSaver UserSaver,
 	userProvider UserProvider,
 	jwtService TokenProvider,
+	hasher PasswordHasher,
+	peppers PasswordPeppers,
+	breaches BreachedPasswords,
 	config *config.Config,
 	redisClient Cache,
 	kafkaClient MessageBroker,
 ) *Auth {
-	return &Auth{
+	a := &Auth{
 		userSaver:    userSaver,
 		userProvider: userProvider,
 		jwtService:   jwtService,
+		hasher:       hasher,
+		peppers:      peppers,
+		breaches:     breaches,
 		config:       config,
 		redis:        redisClient,
 		kafka:        kafkaClient,
+		stop:         make(chan struct{}),
+	}
+
+	a.dummyPassHash = sync.OnceValue(func() []byte {
+		hash, err := a.hasher.Hash("dummy password")
+		if err != nil {
+			panic(err)
+		}
+		return hash
+	})
+
+	return a
+}
+
+// Login checks the credentials and starts a new session for the client. The
+// identifier is either the email or the username of the account. Failed
+// attempts are throttled per account and per client address, and too many
+// consecutive ones lock the account.
+func (a *Auth) Login(ctx context.Context, identifier, password string, client models.ClientInfo) (*models.TokenPair, error) {
+	user, err := a.findUser(ctx, identifier)
+	if err != nil && !errors.Is(err, domain_errors.ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	needsRehash, err := a.checkPasswordAttempt(user, identifier, password, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if needsRehash {
+		a.rehashPassword(ctx, user, password)
+	}
+
+	if user.Suspended() {
+		return nil, domain_errors.ErrAccountSuspended
+	}
+
+	if a.config.BlockUnverifiedLogin && !user.EmailVerified() {
+		return nil, domain_errors.ErrEmailNotVerified
+	}
+
+	audience, err := a.resolveAudience(client.Audience)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ClientIP:   client.IP,
+		UserAgent:  client.UserAgent,
+		Audience:   audience,
+	}
+
+	if err = a.redis.CreateSession(session, a.config.RefreshTokenExpireHours); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	tokens, err := a.issueTokens(user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	a.resetAccountThrottle(user.ID)
+	a.resetFailedLogins(ctx, user)
+
+	return tokens, nil
+}
+
+// findUser looks the account up by email when the identifier looks like one,
+// and by username otherwise. Usernames can not contain an @.
+func (a *Auth) findUser(ctx context.Context, identifier string) (*models.User, error) {
+	if strings.Contains(identifier, "@") {
+		return a.userProvider.GetUser(ctx, identifier)
+	}
+
+	return a.userProvider.GetUserByUsername(ctx, identifier)
+}
+
+// resolveAudience checks the requested audience against the configured ones.
+// An empty request falls back to the first configured audience.
+func (a *Auth) resolveAudience(requested string) (string, error) {
+	if requested == "" {
+		if len(a.config.JwtAudiences) == 0 {
+			return "", nil
+		}
+		return a.config.JwtAudiences[0], nil
+	}
+
+	for _, audience := range a.config.JwtAudiences {
+		if audience == requested {
+			return audience, nil
+		}
+	}
+
+	return "", domain_errors.ErrInvalidAudience
+}
+
+// RefreshToken rotates a refresh token into a new token pair. Presenting a
+// token that was already rotated ends the session it belongs to.
+func (a *Auth) RefreshToken(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	key := "refresh:" + opaque.Hash(refreshToken)
+
+	stored, err := a.redis.GetRefreshToken(key)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrInvalidRefreshToken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not load refresh token: %w", err)
+	}
+
+	session, err := a.redis.GetSession(stored.SessionID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrSessionNotFound) {
+			return nil, domain_errors.ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("could not load session: %w", err)
+	}
+
+	firstUse, err := a.redis.MarkRefreshTokenRotated(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not rotate refresh token: %w", err)
+	}
+
+	if !firstUse {
+		log.Printf("refresh token reuse detected for user %s, revoking session %s", stored.UserID, stored.SessionID)
+
+		if err = a.redis.RemoveSession(stored.UserID, stored.SessionID); err != nil {
+			return nil, fmt.Errorf("could not revoke session: %w", err)
+		}
+
+		return nil, domain_errors.ErrRefreshTokenReused
+	}
+
+	user, err := a.userProvider.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+
+	// Suspending revokes the sessions, this covers a refresh racing with it
+	if user.Suspended() {
+		return nil, domain_errors.ErrInvalidRefreshToken
+	}
+
+	session.LastSeenAt = time.Now()
+	if err = a.redis.TouchSession(session, a.config.RefreshTokenExpireHours); err != nil {
+		if errors.Is(err, domain_errors.ErrSessionNotFound) {
+			return nil, domain_errors.ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return a.issueTokens(user, session)
 }
 
-func (a *Auth) Login(ctx context.Context, email, password string) (string, error) {
-	user, err := a.userProvider.GetUser(ctx, email)
+// issueTokens creates an access token and a refresh token belonging to the given session
+func (a *Auth) issueTokens(user *models.User, session *models.Session) (*models.TokenPair, error) {
+	token, duration, err := a.jwtService.NewToken(user, session.ID, session.Audience)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refreshToken, err := opaque.NewToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
 
+	err = a.redis.StoreRefreshToken("refresh:"+opaque.Hash(refreshToken), &models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: session.ID,
+	}, a.config.RefreshTokenExpireHours)
 	if err != nil {
-		return "", fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
-	if err = bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		return "", domain_errors.ErrInvalidCredentials
+	return &models.TokenPair{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    duration,
+	}, nil
+}
+
+// ValidateToken returns the claims of the token after checking that neither
+// the token nor its session has been revoked. A non-empty audience must be one
+// the token was issued for. Rejected tokens yield an error matching
+// domain_errors.ErrInvalidToken that tells why.
+func (a *Auth) ValidateToken(token string, audience string) (*models.TokenClaims, error) {
+	claims, err := a.jwtService.ValidateToken(token, audience)
+	if err != nil {
+		return nil, err
 	}
 
-	token, duration, err := a.jwtService.NewToken(user)
+	revoked, err := a.isRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, domain_errors.ErrTokenRevoked
+	}
 
+	active, err := a.isSessionActive(claims)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
-	a.redis.StoreToken("token:"+token, user.ID, duration)
+	if !active {
+		return nil, domain_errors.ErrTokenRevoked
+	}
 
-	return token, nil
+	return claims, nil
 }
 
-func (a *Auth) ValidateToken(token string) uuid.UUID {
-	return a.jwtService.ValidateToken(token)
+// isRevoked consults the revocation store. When the store is unreachable the
+// configured policy decides whether the token is accepted.
+func (a *Auth) isRevoked(claims *models.TokenClaims) (bool, error) {
+	if claims.TokenID == "" {
+		return false, nil
+	}
+
+	revoked, err := a.redis.IsTokenRevoked("revoked:" + claims.TokenID)
+	if err != nil {
+		if a.config.RevocationFailOpen {
+			log.Printf("failed to check token revocation: %v", err)
+			return false, nil
+		}
+		return false, fmt.Errorf("could not check token revocation: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// Jwks returns the public keys other services use to verify access tokens
+func (a *Auth) Jwks() []models.JSONWebKey {
+	return a.jwtService.Jwks()
 }
 
 func (a *Auth) Register(
@@ -107,41 +424,93 @@ func (a *Auth) Register(
 	username string,
 	password string,
 ) (userId uuid.UUID, err error) {
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if _, err = a.CheckPasswordStrength(password, email, username); err != nil {
+		return uuid.Nil, err
+	}
+
+	passHash, pepperVersion, err := a.hashPassword(password)
 	if err != nil {
 		log.Println("failed to generate password hash", err)
-		return uuid.Nil, fmt.Errorf("failed to generate password hash")
+		return uuid.Nil, fmt.Errorf("failed to generate password hash: %w", err)
 	}
 
-	id, err := a.userSaver.SaveUser(ctx, email, passHash)
+	id, err := a.userSaver.SaveUser(ctx, email, username, passHash, pepperVersion)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("could not register new user: %w", err)
 	}
 
-	event := &UserCreatedEvent{
+	a.publish(TopicUserCreated, username, &UserCreatedEvent{
 		UserID:   id,
 		Username: username,
+	})
+
+	// The user can ask for another email, so registration does not fail here
+	if err = a.requestEmailVerification(id, email); err != nil {
+		log.Printf("failed to request email verification: %v", err)
 	}
+
+	return id, nil
+}
+
+// runInBackground runs the task once the request has returned, so that its
+// duration does not show in the response time
+func (a *Auth) runInBackground(task func(ctx context.Context)) {
+	a.background.Add(1)
+
+	go func() {
+		defer a.background.Done()
+		task(context.Background())
+	}()
+}
+
+// publish sends the event to the topic in the background
+func (a *Auth) publish(topic string, key string, event any) {
+	msg, err := newMessage(topic, key, event)
+	if err != nil {
+		log.Printf("failed to encode %s event: %v", topic, err)
+		return
+	}
+
+	go func() {
+		if err := a.kafka.Produce(msg); err != nil {
+			log.Printf("failed to publish %s event: %v", topic, err)
+		}
+	}()
+}
+
+func newMessage(topic string, key string, event any) (kafka.Message, error) {
 	data, err := json.Marshal(event)
 	if err != nil {
-		return uuid.Nil, err
+		return kafka.Message{}, err
 	}
 
-	msg := kafka.Message{
-		Key:   []byte(event.Username),
+	return kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
 		Value: data,
 		Time:  time.Now(),
-	}
-
-	go a.kafka.Produce(msg)
-
-	return id, nil
+	}, nil
 }
 
+// Logout revokes the access token until it expires and ends the session it
+// belongs to, so that its refresh token can no longer be used either
 func (a *Auth) Logout(token string) error {
-	err := a.redis.RemoveToken("token:" + token)
+	// An expired access token still names the session to end
+	claims, err := a.jwtService.ValidateTokenSignature(token)
 	if err != nil {
-		return fmt.Errorf("could not remove token from redis: %w", err)
+		return nil
+	}
+
+	if claims.TokenID != "" && time.Until(claims.ExpiresAt) > 0 {
+		if err := a.redis.RevokeToken("revoked:"+claims.TokenID, time.Until(claims.ExpiresAt)); err != nil {
+			return fmt.Errorf("could not revoke token: %w", err)
+		}
+	}
+
+	if claims.SessionID != uuid.Nil {
+		if err := a.redis.RemoveSession(claims.UserID, claims.SessionID); err != nil {
+			return fmt.Errorf("could not remove session from redis: %w", err)
+		}
 	}
 
 	return nil
@@ -4,13 +4,21 @@ import (
 	configProvider "auth-service/internal/config"
 	domain_errors "auth-service/internal/domain/errors"
 	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
+	"auth-service/internal/lib/passhash"
+	"auth-service/internal/lib/pepper"
 	"context"
 	"errors"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type MockUserProvider struct {
@@ -29,6 +37,26 @@ type MockTokenProvider struct {
 	mock.Mock
 }
 
+type MockMessageBroker struct {
+	mock.Mock
+}
+
+// spyHasher records the hashes passwords are compared against
+type spyHasher struct {
+	PasswordHasher
+
+	mu       sync.Mutex
+	compared [][]byte
+}
+
+func (h *spyHasher) Verify(password string, hash []byte) (bool, bool, error) {
+	h.mu.Lock()
+	h.compared = append(h.compared, hash)
+	h.mu.Unlock()
+
+	return h.PasswordHasher.Verify(password, hash)
+}
+
 type AuthTestSuite struct {
 	suite.Suite
 	ctx              context.Context
@@ -37,6 +65,7 @@ type AuthTestSuite struct {
 	mockCache        *MockCache
 	config           *configProvider.Config
 	mockjwtService   *MockTokenProvider
+	mockKafka        *MockMessageBroker
 	authService      *Auth
 	expectedUser     *models.User
 }
@@ -50,48 +79,310 @@ func (m *MockUserProvider) GetUser(ctx context.Context, email string) (*models.U
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserProvider) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserProvider) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserProvider) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserProvider) GetPasswordHistory(
+	ctx context.Context,
+	userID uuid.UUID,
+	limit int,
+	retiredAfter time.Time,
+) ([]models.PasswordHash, error) {
+	args := m.Called(ctx, userID, limit, retiredAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]models.PasswordHash), args.Error(1)
+}
+
+func (m *MockUserProvider) GetDeletedUser(ctx context.Context, identifier string) (*models.User, error) {
+	args := m.Called(ctx, identifier)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *MockUserSaver) SaveUser(
 	ctx context.Context,
 	email string,
 	username string,
 	passHash []byte,
-	firstName string,
-	lastName string,
-) (int64, error) {
-	args := m.Called(ctx, email, username, string(passHash), firstName, lastName)
+	pepperVersion int,
+) (uuid.UUID, error) {
+	args := m.Called(ctx, email, username, string(passHash), pepperVersion)
+
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockUserSaver) SetEmailVerified(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error {
+	args := m.Called(ctx, id, email, verifiedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) UpdatePassword(ctx context.Context, id uuid.UUID, passHash []byte, pepperVersion int) error {
+	args := m.Called(ctx, id, passHash, pepperVersion)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) SetPassword(
+	ctx context.Context,
+	id uuid.UUID,
+	passHash []byte,
+	pepperVersion int,
+	historySize int,
+	retiredAfter time.Time,
+) error {
+	args := m.Called(ctx, id, passHash, pepperVersion, historySize, retiredAfter)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) SetPendingEmail(ctx context.Context, id uuid.UUID, email string) error {
+	args := m.Called(ctx, id, email)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) ConfirmEmailChange(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) (string, error) {
+	args := m.Called(ctx, id, email, verifiedAt)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserSaver) RestoreEmail(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error {
+	args := m.Called(ctx, id, email, verifiedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) DeleteUser(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
+	args := m.Called(ctx, id, deletedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) SuspendUser(ctx context.Context, id uuid.UUID, reason string, until time.Time) error {
+	args := m.Called(ctx, id, reason, until)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) UnsuspendUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) RecordFailedLogin(
+	ctx context.Context,
+	id uuid.UUID,
+	failedAt time.Time,
+	windowStart time.Time,
+	maxFailures int,
+	lockedUntil time.Time,
+) (bool, error) {
+	args := m.Called(ctx, id, failedAt, windowStart, maxFailures, lockedUntil)
+	return args.Bool(0), args.Error(1)
+}
 
-	if args.Get(0) == 0 {
+func (m *MockUserSaver) ResetFailedLogins(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserSaver) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// PurgeDeletedUsers announces the users it is stubbed with and, like the
+// storage, only reports them purged once the announcement succeeds
+func (m *MockUserSaver) PurgeDeletedUsers(
+	ctx context.Context,
+	deletedBefore time.Time,
+	limit int,
+	announce func(users []*models.User) error,
+) (int, error) {
+	args := m.Called(ctx, deletedBefore, limit)
+	if args.Error(1) != nil {
 		return 0, args.Error(1)
 	}
 
-	return args.Get(0).(int64), args.Error(1)
+	users := args.Get(0).([]*models.User)
+	if err := announce(users); err != nil {
+		return 0, err
+	}
+
+	return len(users), nil
+}
+
+func (m *MockCache) StoreRefreshToken(key string, token *models.RefreshToken, ttl time.Duration) error {
+	args := m.Called(key, token, ttl)
+	return args.Error(0)
 }
 
-func (m *MockCache) StoreToken(key string, value int64, ttl time.Duration) {
-	m.Called(key, value, ttl)
+func (m *MockCache) GetRefreshToken(key string) (*models.RefreshToken, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
 }
 
-func (m *MockCache) RemoveToken(key string) error {
+func (m *MockCache) MarkRefreshTokenRotated(key string) (bool, error) {
 	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCache) CreateSession(session *models.Session, ttl time.Duration) error {
+	args := m.Called(session, ttl)
 	return args.Error(0)
 }
 
-func (m *MockTokenProvider) NewToken(user *models.User) (string, time.Duration, error) {
-	args := m.Called(user)
+func (m *MockCache) GetSession(id uuid.UUID) (*models.Session, error) {
+	args := m.Called(id)
 	if args.Get(0) == nil {
-		return "", 0, args.Error(2)
+		return nil, args.Error(1)
 	}
 
-	if args.Get(1) == 0 {
-		return "", 0, args.Error(2)
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockCache) TouchSession(session *models.Session, ttl time.Duration) error {
+	args := m.Called(session, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCache) ListSessions(userID uuid.UUID) ([]*models.Session, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
 
-	return args.Get(0).(string), args.Get(1).(time.Duration), args.Error(2)
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockCache) RemoveSession(userID uuid.UUID, id uuid.UUID) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockCache) RemoveUserSessions(userID uuid.UUID, except ...uuid.UUID) error {
+	args := m.Called(userID, except)
+	return args.Error(0)
+}
+
+func (m *MockCache) StoreOneTimeToken(key string, token *models.OneTimeToken, ttl time.Duration) error {
+	args := m.Called(key, token, ttl)
+	return args.Error(0)
 }
 
-func (m *MockTokenProvider) ValidateToken(tokenString string) int64 {
+func (m *MockCache) GetOneTimeToken(key string) (*models.OneTimeToken, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.OneTimeToken), args.Error(1)
+}
+
+func (m *MockCache) ConsumeOneTimeToken(key string) (*models.OneTimeToken, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.OneTimeToken), args.Error(1)
+}
+
+func (m *MockCache) ReserveLoginAttempt(
+	id string,
+	windows []models.LoginWindow,
+	at time.Time,
+	period time.Duration,
+	backoff models.LoginBackoff,
+) (time.Duration, error) {
+	args := m.Called(id, windows, at, period, backoff)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockCache) ReleaseLoginAttempt(id string, keys []string) error {
+	args := m.Called(id, keys)
+	return args.Error(0)
+}
+
+func (m *MockCache) ResetLoginFailures(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockCache) RevokeToken(key string, ttl time.Duration) error {
+	args := m.Called(key, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCache) IsTokenRevoked(key string) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenProvider) NewToken(user *models.User, sessionID uuid.UUID, audience string) (string, time.Duration, error) {
+	args := m.Called(user, sessionID, audience)
+	return args.String(0), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockTokenProvider) ValidateToken(tokenString string, audience string) (*models.TokenClaims, error) {
+	args := m.Called(tokenString, audience)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.TokenClaims), args.Error(1)
+}
+
+func (m *MockTokenProvider) ValidateTokenSignature(tokenString string) (*models.TokenClaims, error) {
 	args := m.Called(tokenString)
-	return args.Get(0).(int64)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.TokenClaims), args.Error(1)
+}
+
+func (m *MockTokenProvider) Jwks() []models.JSONWebKey {
+	args := m.Called()
+	return args.Get(0).([]models.JSONWebKey)
+}
+
+func (m *MockMessageBroker) Produce(msg kafka.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
 }
 
 func (suite *AuthTestSuite) SetupTest() {
@@ -100,15 +391,43 @@ func (suite *AuthTestSuite) SetupTest() {
 	suite.mockUserSaver = new(MockUserSaver)
 	suite.mockCache = new(MockCache)
 	suite.mockjwtService = new(MockTokenProvider)
-	suite.authService = New(suite.mockUserSaver, suite.mockUserProvider, suite.mockjwtService, suite.config, suite.mockCache)
+	suite.mockKafka = new(MockMessageBroker)
+	suite.config = &configProvider.Config{
+		AccessTokenExpireMinutes: 15 * time.Minute,
+		RefreshTokenExpireHours:  24 * time.Hour,
+		JwtAudiences:             []string{"smap-web", "smap-mobile"},
+		VerificationTokenTTL:     24 * time.Hour,
+		PasswordResetTokenTTL:    30 * time.Minute,
+		EmailRevertTokenTTL:      48 * time.Hour,
+		AccountDeletionGrace:     30 * 24 * time.Hour,
+		PasswordMinLength:        8,
+		PasswordMaxLength:        128,
+		PasswordMinEntropyBits:   30,
+		PasswordHistorySize:      5,
+		PasswordHistoryRetention: 365 * 24 * time.Hour,
+	}
+	// The stored test hash is bcrypt with the default cost, so logins do not rehash
+	hasher, err := passhash.New(passhash.AlgorithmBcrypt, passhash.Argon2Params{}, bcrypt.DefaultCost)
+	suite.Require().NoError(err)
+	peppers, err := pepper.New(nil, pepper.VersionNone)
+	suite.Require().NoError(err)
+
+	suite.authService = New(
+		suite.mockUserSaver,
+		suite.mockUserProvider,
+		suite.mockjwtService,
+		hasher,
+		peppers,
+		nil,
+		suite.config,
+		suite.mockCache,
+		suite.mockKafka,
+	)
 
 	suite.expectedUser = &models.User{
-		ID:        1,
-		Email:     "john_doe@test.com",
-		Username:  "JDoe",
-		FirstName: "John",
-		LastName:  "Doe",
-		PassHash:  []byte("$2a$10$GUiALc4rDfiZAqri6z8GQOzHYwQc4CTzA4EEcA98QYIW7udqqW.xO"),
+		ID:       uuid.New(),
+		Email:    "john_doe@test.com",
+		PassHash: []byte("$2a$10$GUiALc4rDfiZAqri6z8GQOzHYwQc4CTzA4EEcA98QYIW7udqqW.xO"),
 	}
 }
 
@@ -116,62 +435,431 @@ func (suite *AuthTestSuite) TearDownTest() {
 	suite.mockUserProvider.AssertExpectations(suite.T())
 }
 
+func (suite *AuthTestSuite) expectTokensIssued() {
+	suite.mockjwtService.On("NewToken", suite.expectedUser, mock.Anything, mock.Anything).Return("token", 15*time.Minute, nil)
+	suite.mockCache.On("StoreRefreshToken", mock.Anything, mock.Anything, 24*time.Hour).Return(nil)
+}
+
 func (suite *AuthTestSuite) TestAuth_Login_Success() {
+	client := models.ClientInfo{IP: "10.0.0.1", UserAgent: "smap-ios/1.0"}
 	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
-	suite.mockCache.On("StoreToken", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-	suite.mockjwtService.On("NewToken", suite.expectedUser).Return("token", time.Duration(24)*time.Hour, nil)
+	suite.mockCache.On("CreateSession", mock.MatchedBy(func(session *models.Session) bool {
+		return session.UserID == suite.expectedUser.ID &&
+			session.ClientIP == client.IP &&
+			session.UserAgent == client.UserAgent &&
+			session.Audience == "smap-web"
+	}), 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
 
-	token, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "password")
+	tokens, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "password", client)
 
 	suite.NoError(err)
-	suite.NotNil(token)
-	suite.NotEmpty(token)
+	suite.Equal("token", tokens.AccessToken)
+	suite.NotEmpty(tokens.RefreshToken)
+	suite.Equal(15*time.Minute, tokens.ExpiresIn)
 	suite.mockUserSaver.AssertExpectations(suite.T())
 	suite.mockCache.AssertExpectations(suite.T())
-	suite.mockjwtService.AssertExpectations(suite.T())
+	suite.mockjwtService.AssertCalled(suite.T(), "NewToken", suite.expectedUser, mock.Anything, "smap-web")
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_RequestedAudience() {
+	client := models.ClientInfo{Audience: "smap-mobile"}
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockCache.On("CreateSession", mock.MatchedBy(func(session *models.Session) bool {
+		return session.Audience == "smap-mobile"
+	}), 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	_, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "password", client)
+
+	suite.NoError(err)
+	suite.mockjwtService.AssertCalled(suite.T(), "NewToken", suite.expectedUser, mock.Anything, "smap-mobile")
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_UnknownAudience() {
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "password", models.ClientInfo{Audience: "billing"})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidAudience)
+	suite.Nil(tokens)
+	suite.mockCache.AssertNotCalled(suite.T(), "CreateSession")
 }
 
 func (suite *AuthTestSuite) TestAuth_Login_InvalidPassword() {
 	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
 
-	token, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "wrong_password")
+	tokens, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "wrong_password", models.ClientInfo{})
 
 	suite.Error(err)
 	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
-	suite.Empty(token)
+	suite.Nil(tokens)
 	suite.mockUserSaver.AssertNotCalled(suite.T(), "SaveUser")
 }
 
 func (suite *AuthTestSuite) TestAuth_Login_UserNotFound() {
 	suite.mockUserProvider.On("GetUser", suite.ctx, "wrong_user@test.com").Return(nil, domain_errors.ErrUserNotFound)
 
-	token, err := suite.authService.Login(suite.ctx, "wrong_user@test.com", "password")
+	tokens, err := suite.authService.Login(suite.ctx, "wrong_user@test.com", "password", models.ClientInfo{})
 
-	suite.Error(err)
-	suite.Empty(token)
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.Nil(tokens)
 	suite.mockUserSaver.AssertNotCalled(suite.T(), "SaveUser")
 }
 
+func (suite *AuthTestSuite) TestAuth_Login_ByUsername() {
+	suite.mockUserProvider.On("GetUserByUsername", suite.ctx, "JDoe").Return(suite.expectedUser, nil)
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.Login(suite.ctx, "JDoe", "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.Equal("token", tokens.AccessToken)
+	suite.mockUserProvider.AssertNotCalled(suite.T(), "GetUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_UnknownUsername() {
+	suite.mockUserProvider.On("GetUserByUsername", suite.ctx, "nobody").Return(nil, domain_errors.ErrUserNotFound)
+
+	tokens, err := suite.authService.Login(suite.ctx, "nobody", "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.Nil(tokens)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_DummyHashMatchesRealCost() {
+	realCost, err := bcrypt.Cost(suite.expectedUser.PassHash)
+	suite.NoError(err)
+
+	dummyCost, err := bcrypt.Cost(suite.authService.dummyPassHash())
+	suite.NoError(err)
+
+	suite.Equal(realCost, dummyCost)
+}
+
+// The response time of an unknown account must not stand out from the one of
+// a wrong password. The default hasher is argon2id, which takes far less time
+// with these parameters than a bcrypt dummy hash would.
+func (suite *AuthTestSuite) TestAuth_Login_UnknownAccountTiming() {
+	if testing.Short() {
+		suite.T().Skip("timing test")
+	}
+
+	hasher, err := passhash.New(passhash.AlgorithmArgon2id, passhash.Argon2Params{
+		Memory:      8 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+	}, bcrypt.DefaultCost)
+	suite.Require().NoError(err)
+	suite.authService.hasher = hasher
+	suite.enableLockout()
+
+	suite.expectedUser.PassHash, err = hasher.Hash("password")
+	suite.Require().NoError(err)
+
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserProvider.On("GetUser", suite.ctx, "nobody@test.com").Return(nil, domain_errors.ErrUserNotFound)
+	// Counting the failure is a locking transaction, made slow here so that
+	// it shows if it runs before the login returns. The account stays below
+	// the threshold.
+	suite.mockUserSaver.On("RecordFailedLogin", suite.ctx, suite.expectedUser.ID, mock.Anything, mock.Anything, 3, mock.Anything).
+		After(10*time.Millisecond).
+		Return(false, nil)
+
+	login := func(email string) (time.Duration, error) {
+		start := time.Now()
+		_, err := suite.authService.Login(suite.ctx, email, "wrong password", models.ClientInfo{})
+
+		return time.Since(start), err
+	}
+
+	timeLogin := func(email string) time.Duration {
+		elapsed, err := login(email)
+		suite.Require().ErrorIs(err, domain_errors.ErrInvalidCredentials)
+
+		return elapsed
+	}
+
+	// Warm up, the first unknown login makes the dummy hash
+	timeLogin("nobody@test.com")
+	timeLogin(suite.expectedUser.Email)
+
+	const samples = 31
+	known := make([]time.Duration, 0, samples)
+	unknown := make([]time.Duration, 0, samples)
+
+	// Alternating keeps drift in the machine load from favouring one side
+	for i := range samples {
+		if i%2 == 0 {
+			known = append(known, timeLogin(suite.expectedUser.Email))
+			unknown = append(unknown, timeLogin("nobody@test.com"))
+		} else {
+			unknown = append(unknown, timeLogin("nobody@test.com"))
+			known = append(known, timeLogin(suite.expectedUser.Email))
+		}
+	}
+	suite.authService.background.Wait()
+
+	ratio := float64(median(unknown)) / float64(median(known))
+	suite.InDelta(1, ratio, 0.25, "unknown account took %v, wrong password took %v", median(unknown), median(known))
+	suite.mockUserSaver.AssertCalled(suite.T(), "RecordFailedLogin", suite.ctx, suite.expectedUser.ID, mock.Anything, mock.Anything, 3, mock.Anything)
+
+	// Once the threshold is reached the error, and so the status code the
+	// gRPC layer maps it to, is still the one of an unknown account
+	suite.expectedUser.LockedUntil = time.Now().Add(time.Hour)
+	_, lockedErr := login(suite.expectedUser.Email)
+	_, unknownErr := login("nobody@test.com")
+	suite.ErrorIs(unknownErr, domain_errors.ErrInvalidCredentials)
+	suite.Equal(unknownErr, lockedErr)
+}
+
+func median(durations []time.Duration) time.Duration {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	return sorted[len(sorted)/2]
+}
+
 func (suite *AuthTestSuite) TestAuth_Login_TokenError() {
 	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
-	suite.mockCache.On("StoreToken", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-	suite.mockjwtService.On("NewToken", suite.expectedUser).Return("", 0, errors.New("some error"))
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.mockjwtService.On("NewToken", suite.expectedUser, mock.Anything, mock.Anything).Return("", time.Duration(0), errors.New("some error"))
 
-	token, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "password")
+	tokens, err := suite.authService.Login(suite.ctx, "john_doe@test.com", "password", models.ClientInfo{})
 
 	suite.Error(err)
-	suite.Empty(token)
-	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.Nil(tokens)
 	suite.mockjwtService.AssertExpectations(suite.T())
-	suite.mockCache.AssertNotCalled(suite.T(), "StoreToken")
+	suite.mockCache.AssertNotCalled(suite.T(), "StoreRefreshToken")
+}
+
+func (suite *AuthTestSuite) storedSession() *models.Session {
+	return &models.Session{
+		ID:         uuid.New(),
+		UserID:     suite.expectedUser.ID,
+		CreatedAt:  time.Now().Add(-time.Hour),
+		LastSeenAt: time.Now().Add(-time.Hour),
+		Audience:   "smap-mobile",
+	}
+}
+
+func (suite *AuthTestSuite) TestAuth_RefreshToken_Success() {
+	session := suite.storedSession()
+	key := "refresh:" + opaque.Hash("refresh")
+
+	suite.mockCache.On("GetRefreshToken", key).Return(&models.RefreshToken{
+		UserID:    suite.expectedUser.ID,
+		SessionID: session.ID,
+	}, nil)
+	suite.mockCache.On("GetSession", session.ID).Return(session, nil)
+	suite.mockCache.On("MarkRefreshTokenRotated", key).Return(true, nil)
+	suite.mockCache.On("TouchSession", session, 24*time.Hour).Return(nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.RefreshToken(suite.ctx, "refresh")
+
+	suite.NoError(err)
+	suite.Equal("token", tokens.AccessToken)
+	suite.NotEqual("refresh", tokens.RefreshToken)
+	suite.WithinDuration(time.Now(), session.LastSeenAt, time.Second)
+	suite.mockjwtService.AssertCalled(suite.T(), "NewToken", suite.expectedUser, session.ID, "smap-mobile")
+	suite.mockCache.AssertCalled(suite.T(), "StoreRefreshToken", mock.Anything, &models.RefreshToken{
+		UserID:    suite.expectedUser.ID,
+		SessionID: session.ID,
+	}, 24*time.Hour)
+}
+
+func (suite *AuthTestSuite) TestAuth_RefreshToken_ReuseRevokesSession() {
+	session := suite.storedSession()
+	key := "refresh:" + opaque.Hash("refresh")
+
+	suite.mockCache.On("GetRefreshToken", key).Return(&models.RefreshToken{
+		UserID:    suite.expectedUser.ID,
+		SessionID: session.ID,
+	}, nil)
+	suite.mockCache.On("GetSession", session.ID).Return(session, nil)
+	suite.mockCache.On("MarkRefreshTokenRotated", key).Return(false, nil)
+	suite.mockCache.On("RemoveSession", suite.expectedUser.ID, session.ID).Return(nil)
+
+	tokens, err := suite.authService.RefreshToken(suite.ctx, "refresh")
+
+	suite.ErrorIs(err, domain_errors.ErrRefreshTokenReused)
+	suite.Nil(tokens)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.mockjwtService.AssertNotCalled(suite.T(), "NewToken")
+}
+
+func (suite *AuthTestSuite) TestAuth_RefreshToken_RevokedSession() {
+	sessionID := uuid.New()
+	key := "refresh:" + opaque.Hash("refresh")
+
+	suite.mockCache.On("GetRefreshToken", key).Return(&models.RefreshToken{
+		UserID:    suite.expectedUser.ID,
+		SessionID: sessionID,
+	}, nil)
+	suite.mockCache.On("GetSession", sessionID).Return(nil, domain_errors.ErrSessionNotFound)
+
+	tokens, err := suite.authService.RefreshToken(suite.ctx, "refresh")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidRefreshToken)
+	suite.Nil(tokens)
+	suite.mockCache.AssertNotCalled(suite.T(), "MarkRefreshTokenRotated", key)
+}
+
+func (suite *AuthTestSuite) TestAuth_RefreshToken_SessionRemovedBeforeTouch() {
+	session := suite.storedSession()
+	key := "refresh:" + opaque.Hash("refresh")
+
+	suite.mockCache.On("GetRefreshToken", key).Return(&models.RefreshToken{
+		UserID:    suite.expectedUser.ID,
+		SessionID: session.ID,
+	}, nil)
+	suite.mockCache.On("GetSession", session.ID).Return(session, nil)
+	suite.mockCache.On("MarkRefreshTokenRotated", key).Return(true, nil)
+	suite.mockCache.On("TouchSession", session, 24*time.Hour).Return(domain_errors.ErrSessionNotFound)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	tokens, err := suite.authService.RefreshToken(suite.ctx, "refresh")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidRefreshToken)
+	suite.Nil(tokens)
+	suite.mockjwtService.AssertNotCalled(suite.T(), "NewToken")
+}
+
+func (suite *AuthTestSuite) TestAuth_RefreshToken_Unknown() {
+	suite.mockCache.On("GetRefreshToken", mock.Anything).Return(nil, domain_errors.ErrInvalidRefreshToken)
+
+	tokens, err := suite.authService.RefreshToken(suite.ctx, "unknown")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidRefreshToken)
+	suite.Nil(tokens)
+}
+
+func (suite *AuthTestSuite) validClaims() *models.TokenClaims {
+	return &models.TokenClaims{
+		UserID:    suite.expectedUser.ID,
+		Email:     suite.expectedUser.Email,
+		TokenID:   "jti",
+		SessionID: uuid.New(),
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	}
+}
+
+// expectActiveSession makes the session of the claims exist and recently used
+func (suite *AuthTestSuite) expectActiveSession(claims *models.TokenClaims) {
+	suite.mockCache.On("GetSession", claims.SessionID).Return(&models.Session{
+		ID:         claims.SessionID,
+		UserID:     claims.UserID,
+		LastSeenAt: time.Now(),
+	}, nil)
 }
 
 func (suite *AuthTestSuite) TestAuth_Login_TokenValid() {
-	suite.mockjwtService.On("ValidateToken", mock.Anything).Return(suite.expectedUser.ID)
-	uid := suite.authService.ValidateToken("test")
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+
+	result, err := suite.authService.ValidateToken("test", "")
+
+	suite.NoError(err)
+	suite.Equal(claims, result)
+	suite.mockCache.AssertNotCalled(suite.T(), "TouchSession", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ValidateToken_TouchesIdleSession() {
+	claims := suite.validClaims()
+	session := &models.Session{
+		ID:         claims.SessionID,
+		UserID:     claims.UserID,
+		LastSeenAt: time.Now().Add(-time.Hour),
+	}
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.mockCache.On("GetSession", claims.SessionID).Return(session, nil)
+	suite.mockCache.On("TouchSession", session, time.Duration(0)).Return(nil)
+
+	_, err := suite.authService.ValidateToken("test", "")
+
+	suite.NoError(err)
+	suite.WithinDuration(time.Now(), session.LastSeenAt, time.Second)
+}
+
+func (suite *AuthTestSuite) TestAuth_ValidateToken_SessionRemovedBeforeTouch() {
+	claims := suite.validClaims()
+	session := &models.Session{
+		ID:         claims.SessionID,
+		UserID:     claims.UserID,
+		LastSeenAt: time.Now().Add(-time.Hour),
+	}
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.mockCache.On("GetSession", claims.SessionID).Return(session, nil)
+	suite.mockCache.On("TouchSession", session, time.Duration(0)).Return(domain_errors.ErrSessionNotFound)
+
+	result, err := suite.authService.ValidateToken("test", "")
+
+	suite.ErrorIs(err, domain_errors.ErrTokenRevoked)
+	suite.Nil(result)
+}
+
+func (suite *AuthTestSuite) TestAuth_ValidateToken_RevokedSession() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.mockCache.On("GetSession", claims.SessionID).Return(nil, domain_errors.ErrSessionNotFound)
+
+	result, err := suite.authService.ValidateToken("test", "")
+
+	suite.ErrorIs(err, domain_errors.ErrTokenRevoked)
+	suite.Nil(result)
+}
+
+func (suite *AuthTestSuite) TestAuth_ValidateToken_Invalid() {
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(nil, domain_errors.ErrTokenExpired)
+
+	result, err := suite.authService.ValidateToken("test", "")
 
-	suite.NotNil(uid)
-	suite.IsType(int64(0), uid)
+	suite.ErrorIs(err, domain_errors.ErrTokenExpired)
+	suite.ErrorIs(err, domain_errors.ErrInvalidToken)
+	suite.Nil(result)
+	suite.mockCache.AssertNotCalled(suite.T(), "IsTokenRevoked", mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ValidateToken_Revoked() {
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(suite.validClaims(), nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(true, nil)
+
+	result, err := suite.authService.ValidateToken("test", "")
+
+	suite.ErrorIs(err, domain_errors.ErrTokenRevoked)
+	suite.Nil(result)
+}
+
+func (suite *AuthTestSuite) TestAuth_ValidateToken_StoreUnavailableFailsClosed() {
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(suite.validClaims(), nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, errors.New("connection refused"))
+
+	result, err := suite.authService.ValidateToken("test", "")
+
+	suite.Error(err)
+	suite.NotErrorIs(err, domain_errors.ErrInvalidToken)
+	suite.Nil(result)
+}
+
+func (suite *AuthTestSuite) TestAuth_ValidateToken_StoreUnavailableFailsOpen() {
+	claims := suite.validClaims()
+	suite.config.RevocationFailOpen = true
+	suite.mockjwtService.On("ValidateToken", "test", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, errors.New("connection refused"))
+	suite.mockCache.On("GetSession", claims.SessionID).Return(nil, errors.New("connection refused"))
+
+	result, err := suite.authService.ValidateToken("test", "")
+
+	suite.NoError(err)
+	suite.Equal(suite.expectedUser.ID, result.UserID)
 }
 
 func (suite *AuthTestSuite) TestAuth_Login_RegisterSuccess() {
@@ -179,22 +867,52 @@ func (suite *AuthTestSuite) TestAuth_Login_RegisterSuccess() {
 		"SaveUser",
 		suite.ctx,
 		suite.expectedUser.Email,
-		suite.expectedUser.Username,
+		"JDoe",
 		mock.Anything,
-		suite.expectedUser.FirstName,
-		suite.expectedUser.LastName).Return(suite.expectedUser.ID, nil)
+		pepper.VersionNone).Return(suite.expectedUser.ID, nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.AnythingOfType("string"), &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, 24*time.Hour).Return(nil)
+	produced := suite.expectProduce()
 
 	uid, err := suite.authService.Register(
 		suite.ctx,
 		suite.expectedUser.Email,
-		suite.expectedUser.Username,
+		"JDoe",
 		"password",
-		suite.expectedUser.FirstName,
-		suite.expectedUser.LastName,
 	)
 	suite.NoError(err)
-	suite.NotNil(uid)
-	suite.NotEmpty(uid)
+	suite.Equal(suite.expectedUser.ID, uid)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.ElementsMatch([]string{TopicUserCreated, TopicEmailVerificationRequested}, suite.receive(produced, 2))
+}
+
+// expectProduce accepts every message and passes it on, as messages are
+// published in the background
+func (suite *AuthTestSuite) expectProduce() <-chan kafka.Message {
+	produced := make(chan kafka.Message, 10)
+	suite.mockKafka.On("Produce", mock.Anything).Run(func(args mock.Arguments) {
+		produced <- args.Get(0).(kafka.Message)
+	}).Return(nil)
+
+	return produced
+}
+
+// receive waits for count messages and returns their topics
+func (suite *AuthTestSuite) receive(produced <-chan kafka.Message, count int) []string {
+	var topics []string
+
+	for range count {
+		select {
+		case msg := <-produced:
+			topics = append(topics, msg.Topic)
+		case <-time.After(time.Second):
+			suite.FailNow("message was not published")
+		}
+	}
+
+	return topics
 }
 
 func (suite *AuthTestSuite) TestAuth_Login_SaveUserError() {
@@ -204,31 +922,78 @@ func (suite *AuthTestSuite) TestAuth_Login_SaveUserError() {
 		mock.Anything,
 		mock.Anything,
 		mock.Anything,
-		mock.Anything,
-		mock.Anything).Return(0, errors.New("some error"))
+		mock.Anything).Return(uuid.Nil, errors.New("some error"))
 
 	uid, err := suite.authService.Register(
 		suite.ctx,
 		suite.expectedUser.Email,
-		suite.expectedUser.Username,
+		"JDoe",
 		"password",
-		suite.expectedUser.FirstName,
-		suite.expectedUser.LastName,
 	)
 
 	suite.Error(err)
-	suite.Equal(int64(0), uid)
+	suite.Equal(uuid.Nil, uid)
+	suite.mockKafka.AssertNotCalled(suite.T(), "Produce", mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_RegisterUsernameExists() {
+	suite.mockUserSaver.On(
+		"SaveUser",
+		suite.ctx,
+		suite.expectedUser.Email,
+		"JDoe",
+		mock.Anything,
+		pepper.VersionNone).Return(uuid.Nil, domain_errors.ErrUserUsernameExists)
+
+	uid, err := suite.authService.Register(
+		suite.ctx,
+		suite.expectedUser.Email,
+		"JDoe",
+		"password",
+	)
+
+	suite.ErrorIs(err, domain_errors.ErrUserUsernameExists)
+	suite.Equal(uuid.Nil, uid)
+	suite.mockKafka.AssertNotCalled(suite.T(), "Produce", mock.Anything)
 }
 
 func (suite *AuthTestSuite) TestAuth_Login_LogoutSuccess() {
-	suite.mockCache.On("RemoveToken", mock.Anything).Return(nil)
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateTokenSignature", "token").Return(claims, nil)
+	suite.mockCache.On("RevokeToken", "revoked:jti", mock.MatchedBy(func(ttl time.Duration) bool {
+		return ttl > 9*time.Minute && ttl <= 10*time.Minute
+	})).Return(nil)
+	suite.mockCache.On("RemoveSession", suite.expectedUser.ID, claims.SessionID).Return(nil)
+
+	err := suite.authService.Logout("token")
+	suite.NoError(err)
+	suite.mockCache.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_LogoutExpiredToken() {
+	claims := suite.validClaims()
+	claims.ExpiresAt = time.Now().Add(-time.Minute)
+	suite.mockjwtService.On("ValidateTokenSignature", "token").Return(claims, nil)
+	suite.mockCache.On("RemoveSession", suite.expectedUser.ID, claims.SessionID).Return(nil)
+
+	err := suite.authService.Logout("token")
+	suite.NoError(err)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.mockCache.AssertNotCalled(suite.T(), "RevokeToken", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_LogoutInvalidToken() {
+	suite.mockjwtService.On("ValidateTokenSignature", "token").Return(nil, domain_errors.ErrTokenSignatureInvalid)
 
 	err := suite.authService.Logout("token")
 	suite.NoError(err)
+	suite.mockCache.AssertNotCalled(suite.T(), "RevokeToken", mock.Anything, mock.Anything)
+	suite.mockCache.AssertNotCalled(suite.T(), "RemoveSession", mock.Anything, mock.Anything)
 }
 
 func (suite *AuthTestSuite) TestAuth_Login_LogoutFail() {
-	suite.mockCache.On("RemoveToken", mock.Anything).Return(errors.New("some error"))
+	suite.mockjwtService.On("ValidateTokenSignature", "token").Return(suite.validClaims(), nil)
+	suite.mockCache.On("RevokeToken", mock.Anything, mock.Anything).Return(errors.New("some error"))
 
 	err := suite.authService.Logout("token")
 	suite.Error(err)
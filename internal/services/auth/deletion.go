@@ -0,0 +1,163 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// purgeBatchSize bounds how many accounts a single purge statement removes
+const purgeBatchSize = 100
+
+const TopicUserDeleted = "user-deleted"
+
+// UserDeletedEvent tells other services to erase the data they keep about
+// the user. It is published once the grace period is over and the account
+// can no longer be restored.
+type UserDeletedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgedAt  time.Time `json:"purged_at"`
+}
+
+// DeleteAccount deletes the account of the token owner once the password is
+// confirmed and ends every session. The account can be restored until the
+// grace period is over. Wrong passwords are throttled and lock the account
+// the same way failed logins do.
+func (a *Auth) DeleteAccount(ctx context.Context, token string, password string, client models.ClientInfo) error {
+	claims, err := a.ValidateToken(token, "")
+	if err != nil {
+		return err
+	}
+
+	user, err := a.userProvider.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidToken
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if _, err = a.checkPasswordAttempt(user, user.Email, password, client); err != nil {
+		return err
+	}
+
+	if err = a.userSaver.DeleteUser(ctx, user.ID, time.Now()); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if err = a.redis.RemoveUserSessions(user.ID); err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+
+	a.resetAccountThrottle(user.ID)
+	a.resetFailedLogins(ctx, user)
+
+	return nil
+}
+
+// RestoreAccount brings back a deleted account within the grace period. The
+// identifier is either the email or the username of the account. Wrong
+// passwords are throttled and lock the account the same way failed logins do.
+func (a *Auth) RestoreAccount(ctx context.Context, identifier string, password string, client models.ClientInfo) error {
+	user, err := a.userProvider.GetDeletedUser(ctx, identifier)
+	if err != nil && !errors.Is(err, domain_errors.ErrUserNotFound) {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if _, err = a.checkPasswordAttempt(user, identifier, password, client); err != nil {
+		return err
+	}
+
+	// Waiting for the next purge run
+	if time.Since(user.DeletedAt) >= a.config.AccountDeletionGrace {
+		return domain_errors.ErrInvalidCredentials
+	}
+
+	if err = a.userSaver.RestoreUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	a.resetAccountThrottle(user.ID)
+	a.resetFailedLogins(ctx, user)
+
+	return nil
+}
+
+// PurgeDeletedAccounts removes the accounts whose grace period is over and
+// announces each removal
+func (a *Auth) PurgeDeletedAccounts(ctx context.Context) error {
+	for {
+		purged, err := a.userSaver.PurgeDeletedUsers(
+			ctx,
+			time.Now().Add(-a.config.AccountDeletionGrace),
+			purgeBatchSize,
+			a.announcePurge,
+		)
+		if err != nil {
+			return err
+		}
+
+		if purged < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// announcePurge publishes the removal of the users before their rows are
+// deleted. An event that can not be sent fails the batch, which is then
+// announced again by the next purge, so consumers may see it twice.
+func (a *Auth) announcePurge(users []*models.User) error {
+	purgedAt := time.Now()
+
+	for _, user := range users {
+		msg, err := newMessage(TopicUserDeleted, user.ID.String(), &UserDeletedEvent{
+			UserID:    user.ID,
+			DeletedAt: user.DeletedAt,
+			PurgedAt:  purgedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", TopicUserDeleted, err)
+		}
+
+		if err = a.kafka.Produce(msg); err != nil {
+			return fmt.Errorf("failed to publish %s event: %w", TopicUserDeleted, err)
+		}
+	}
+
+	return nil
+}
+
+// StartPurge periodically removes the accounts whose grace period is over.
+// Stop waits for a running purge to finish.
+func (a *Auth) StartPurge() {
+	a.background.Add(1)
+	go func() {
+		defer a.background.Done()
+
+		ticker := time.NewTicker(a.config.AccountPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := a.PurgeDeletedAccounts(context.Background()); err != nil {
+					log.Printf("failed to purge deleted accounts: %v", err)
+				}
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (a *Auth) Stop() {
+	close(a.stop)
+	a.background.Wait()
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AuthTestSuite) TestAuth_DeleteAccount_Success() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("DeleteUser", suite.ctx, suite.expectedUser.ID, mock.Anything).Return(nil)
+	suite.mockCache.On("RemoveUserSessions", suite.expectedUser.ID, []uuid.UUID(nil)).Return(nil)
+
+	err := suite.authService.DeleteAccount(suite.ctx, "token", "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockCache.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_DeleteAccount_WrongPassword() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.DeleteAccount(suite.ctx, "token", "wrong_password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockCache.AssertNotCalled(suite.T(), "RemoveUserSessions", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_DeleteAccount_WrongPasswordCounts() {
+	suite.enableLoginThrottling()
+	suite.enableLockout()
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	suite.expectChangePasswordCaller()
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "10.0.0.1", 0, nil)
+	suite.mockUserSaver.On("RecordFailedLogin", suite.ctx, suite.expectedUser.ID, mock.Anything, mock.Anything, 3, mock.Anything).Return(false, nil)
+
+	err := suite.authService.DeleteAccount(suite.ctx, "token", "wrong_password", client)
+	suite.authService.background.Wait()
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_DeleteAccount_Throttled() {
+	suite.enableLoginThrottling()
+	suite.expectChangePasswordCaller()
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "", 4*time.Second, nil)
+
+	err := suite.authService.DeleteAccount(suite.ctx, "token", "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrTooManyLoginAttempts)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_DeleteAccount_Locked() {
+	suite.enableLockout()
+	suite.expectedUser.LockedUntil = time.Now().Add(time.Hour)
+	hasher := &spyHasher{PasswordHasher: suite.authService.hasher}
+	suite.authService.hasher = hasher
+	suite.expectChangePasswordCaller()
+
+	err := suite.authService.DeleteAccount(suite.ctx, "token", "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.Len(hasher.compared, 1)
+	suite.NotContains(hasher.compared, suite.expectedUser.PassHash)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_RestoreAccount_WithinGracePeriod() {
+	suite.expectedUser.DeletedAt = time.Now().Add(-24 * time.Hour)
+	suite.mockUserProvider.On("GetDeletedUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("RestoreUser", suite.ctx, suite.expectedUser.ID).Return(nil)
+
+	err := suite.authService.RestoreAccount(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_RestoreAccount_GracePeriodOver() {
+	suite.expectedUser.DeletedAt = time.Now().Add(-31 * 24 * time.Hour)
+	suite.mockUserProvider.On("GetDeletedUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	err := suite.authService.RestoreAccount(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "RestoreUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_RestoreAccount_NotDeleted() {
+	suite.mockUserProvider.On("GetDeletedUser", suite.ctx, "JDoe").Return(nil, domain_errors.ErrUserNotFound)
+
+	err := suite.authService.RestoreAccount(suite.ctx, "JDoe", "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "RestoreUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_RestoreAccount_WrongPasswordCounts() {
+	suite.enableLoginThrottling()
+	suite.enableLockout()
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	suite.expectedUser.DeletedAt = time.Now().Add(-24 * time.Hour)
+	suite.mockUserProvider.On("GetDeletedUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "10.0.0.1", 0, nil)
+	suite.mockUserSaver.On("RecordFailedLogin", suite.ctx, suite.expectedUser.ID, mock.Anything, mock.Anything, 3, mock.Anything).Return(false, nil)
+
+	err := suite.authService.RestoreAccount(suite.ctx, suite.expectedUser.Email, "wrong password", client)
+	suite.authService.background.Wait()
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "RestoreUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_RestoreAccount_Throttled() {
+	suite.enableLoginThrottling()
+	suite.mockUserProvider.On("GetDeletedUser", suite.ctx, "JDoe").Return(nil, domain_errors.ErrUserNotFound)
+	suite.expectReserve(identifierThrottleKey("JDoe"), "", 4*time.Second, nil)
+
+	err := suite.authService.RestoreAccount(suite.ctx, "JDoe", "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrTooManyLoginAttempts)
+}
+
+func (suite *AuthTestSuite) TestAuth_RestoreAccount_Locked() {
+	suite.enableLockout()
+	suite.expectedUser.DeletedAt = time.Now().Add(-24 * time.Hour)
+	suite.expectedUser.LockedUntil = time.Now().Add(time.Hour)
+	suite.mockUserProvider.On("GetDeletedUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	err := suite.authService.RestoreAccount(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "RestoreUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_PurgeDeletedAccounts() {
+	deletedAt := time.Now().Add(-31 * 24 * time.Hour).Truncate(time.Second)
+	suite.expectedUser.DeletedAt = deletedAt
+	suite.mockUserSaver.On("PurgeDeletedUsers", suite.ctx, mock.MatchedBy(func(before time.Time) bool {
+		return time.Until(before) < -29*24*time.Hour
+	}), purgeBatchSize).Return([]*models.User{suite.expectedUser}, nil).Once()
+	produced := suite.expectProduce()
+
+	err := suite.authService.PurgeDeletedAccounts(suite.ctx)
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertNumberOfCalls(suite.T(), "PurgeDeletedUsers", 1)
+
+	var event UserDeletedEvent
+	msg := <-produced
+	suite.Equal(TopicUserDeleted, msg.Topic)
+	suite.Equal(suite.expectedUser.ID.String(), string(msg.Key))
+	suite.NoError(json.Unmarshal(msg.Value, &event))
+	suite.Equal(suite.expectedUser.ID, event.UserID)
+	suite.True(deletedAt.Equal(event.DeletedAt))
+}
+
+func (suite *AuthTestSuite) TestAuth_PurgeDeletedAccounts_PublishFails() {
+	suite.expectedUser.DeletedAt = time.Now().Add(-31 * 24 * time.Hour)
+	suite.mockUserSaver.On("PurgeDeletedUsers", suite.ctx, mock.Anything, purgeBatchSize).
+		Return([]*models.User{suite.expectedUser}, nil).Once()
+	suite.mockKafka.On("Produce", mock.Anything).Return(errors.New("broker unavailable"))
+
+	err := suite.authService.PurgeDeletedAccounts(suite.ctx)
+
+	suite.ErrorContains(err, "broker unavailable")
+	suite.mockKafka.AssertNumberOfCalls(suite.T(), "Produce", 1)
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TopicEmailChangeRequested = "user-email-change-requested"
+	TopicEmailChanged         = "user-email-changed"
+)
+
+// EmailChangeRequestedEvent asks the mail service to send the confirmation
+// token to the new address
+type EmailChangeRequestedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EmailChangedEvent notifies the previous address of the change. The revert
+// token moves the account back in case the change was not made by the user.
+type EmailChangedEvent struct {
+	UserID          uuid.UUID `json:"user_id"`
+	PreviousEmail   string    `json:"previous_email"`
+	Email           string    `json:"email"`
+	RevertToken     string    `json:"revert_token"`
+	RevertExpiresAt time.Time `json:"revert_expires_at"`
+}
+
+func emailChangeTokenKey(token string) string {
+	return "change_email:" + opaque.Hash(token)
+}
+
+func emailRevertTokenKey(token string) string {
+	return "revert_email:" + opaque.Hash(token)
+}
+
+// ChangeEmail records the new address of the token owner as pending and sends
+// a confirmation token to it. The address is only used once confirmed. It
+// is not checked against other accounts here, so that the answer does not
+// tell whether the address is registered. ConfirmEmailChange rejects it when
+// it is taken by then.
+func (a *Auth) ChangeEmail(ctx context.Context, token string, email string) error {
+	claims, err := a.ValidateToken(token, "")
+	if err != nil {
+		return err
+	}
+
+	user, err := a.userProvider.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidToken
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if strings.EqualFold(user.Email, email) {
+		return domain_errors.ErrEmailUnchanged
+	}
+
+	if err = a.userSaver.SetPendingEmail(ctx, user.ID, email); err != nil {
+		return fmt.Errorf("failed to set pending email: %w", err)
+	}
+
+	confirmToken, err := a.issueOneTimeToken(emailChangeTokenKey, user.ID, email, a.config.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	a.publish(TopicEmailChangeRequested, user.ID.String(), &EmailChangeRequestedEvent{
+		UserID:    user.ID,
+		Email:     email,
+		Token:     confirmToken,
+		ExpiresAt: time.Now().Add(a.config.VerificationTokenTTL),
+	})
+
+	return nil
+}
+
+// ConfirmEmailChange consumes a confirmation token and swaps the pending
+// address in. The previous address is notified and can revert the change.
+func (a *Auth) ConfirmEmailChange(ctx context.Context, token string) error {
+	stored, err := a.redis.ConsumeOneTimeToken(emailChangeTokenKey(token))
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrOneTimeTokenNotFound) {
+			return domain_errors.ErrInvalidEmailChangeToken
+		}
+		return fmt.Errorf("could not load email change token: %w", err)
+	}
+
+	previousEmail, err := a.userSaver.ConfirmEmailChange(ctx, stored.UserID, stored.Email, time.Now())
+	if err != nil {
+		// Another change was requested since the token was sent
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidEmailChangeToken
+		}
+		if errors.Is(err, domain_errors.ErrUserEmailExists) {
+			return err
+		}
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+
+	revertToken, err := a.issueOneTimeToken(emailRevertTokenKey, stored.UserID, previousEmail, a.config.EmailRevertTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	a.publish(TopicEmailChanged, stored.UserID.String(), &EmailChangedEvent{
+		UserID:          stored.UserID,
+		PreviousEmail:   previousEmail,
+		Email:           stored.Email,
+		RevertToken:     revertToken,
+		RevertExpiresAt: time.Now().Add(a.config.EmailRevertTokenTTL),
+	})
+
+	return nil
+}
+
+// RevertEmailChange consumes a revert token, moves the account back to the
+// previous address and ends every session, as whoever made the change may
+// still be signed in
+func (a *Auth) RevertEmailChange(ctx context.Context, token string) error {
+	stored, err := a.redis.ConsumeOneTimeToken(emailRevertTokenKey(token))
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrOneTimeTokenNotFound) {
+			return domain_errors.ErrInvalidEmailChangeToken
+		}
+		return fmt.Errorf("could not load email revert token: %w", err)
+	}
+
+	if err = a.userSaver.RestoreEmail(ctx, stored.UserID, stored.Email, time.Now()); err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidEmailChangeToken
+		}
+		if errors.Is(err, domain_errors.ErrUserEmailExists) {
+			return err
+		}
+		return fmt.Errorf("failed to restore email: %w", err)
+	}
+
+	if err = a.redis.RemoveUserSessions(stored.UserID); err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+
+	return nil
+}
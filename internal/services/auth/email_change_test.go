@@ -0,0 +1,148 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AuthTestSuite) TestAuth_ChangeEmail_Success() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("SetPendingEmail", suite.ctx, suite.expectedUser.ID, "new@test.com").Return(nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.AnythingOfType("string"), &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "new@test.com",
+	}, 24*time.Hour).Return(nil)
+	produced := suite.expectProduce()
+
+	err := suite.authService.ChangeEmail(suite.ctx, "token", "new@test.com")
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+
+	var event EmailChangeRequestedEvent
+	msg := <-produced
+	suite.Equal(TopicEmailChangeRequested, msg.Topic)
+	suite.NoError(json.Unmarshal(msg.Value, &event))
+	suite.Equal("new@test.com", event.Email)
+	suite.mockCache.AssertCalled(suite.T(), "StoreOneTimeToken", emailChangeTokenKey(event.Token), mock.Anything, 24*time.Hour)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangeEmail_AddressTaken() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("SetPendingEmail", suite.ctx, suite.expectedUser.ID, "taken@test.com").Return(nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.AnythingOfType("string"), &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "taken@test.com",
+	}, 24*time.Hour).Return(nil)
+	produced := suite.expectProduce()
+
+	// A taken address is answered like a free one, the confirmation fails later
+	err := suite.authService.ChangeEmail(suite.ctx, "token", "taken@test.com")
+
+	suite.NoError(err)
+	suite.Equal(TopicEmailChangeRequested, (<-produced).Topic)
+	suite.mockUserProvider.AssertNotCalled(suite.T(), "GetUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangeEmail_Unchanged() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.ChangeEmail(suite.ctx, "token", suite.expectedUser.Email)
+
+	suite.ErrorIs(err, domain_errors.ErrEmailUnchanged)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPendingEmail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmEmailChange_Success() {
+	suite.mockCache.On("ConsumeOneTimeToken", emailChangeTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "new@test.com",
+	}, nil)
+	suite.mockUserSaver.On("ConfirmEmailChange", suite.ctx, suite.expectedUser.ID, "new@test.com", mock.Anything).
+		Return(suite.expectedUser.Email, nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.AnythingOfType("string"), &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, 48*time.Hour).Return(nil)
+	produced := suite.expectProduce()
+
+	err := suite.authService.ConfirmEmailChange(suite.ctx, "token")
+
+	suite.NoError(err)
+
+	var event EmailChangedEvent
+	msg := <-produced
+	suite.Equal(TopicEmailChanged, msg.Topic)
+	suite.NoError(json.Unmarshal(msg.Value, &event))
+	suite.Equal(suite.expectedUser.Email, event.PreviousEmail)
+	suite.Equal("new@test.com", event.Email)
+	suite.mockCache.AssertCalled(suite.T(), "StoreOneTimeToken", emailRevertTokenKey(event.RevertToken), mock.Anything, 48*time.Hour)
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmEmailChange_AddressTakenMeanwhile() {
+	suite.mockCache.On("ConsumeOneTimeToken", emailChangeTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "new@test.com",
+	}, nil)
+	suite.mockUserSaver.On("ConfirmEmailChange", suite.ctx, suite.expectedUser.ID, "new@test.com", mock.Anything).
+		Return("", domain_errors.ErrUserEmailExists)
+
+	err := suite.authService.ConfirmEmailChange(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrUserEmailExists)
+	suite.mockCache.AssertNotCalled(suite.T(), "StoreOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmEmailChange_Superseded() {
+	suite.mockCache.On("ConsumeOneTimeToken", emailChangeTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "old-request@test.com",
+	}, nil)
+	suite.mockUserSaver.On("ConfirmEmailChange", suite.ctx, suite.expectedUser.ID, "old-request@test.com", mock.Anything).
+		Return("", domain_errors.ErrUserNotFound)
+
+	err := suite.authService.ConfirmEmailChange(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidEmailChangeToken)
+}
+
+func (suite *AuthTestSuite) TestAuth_RevertEmailChange_Success() {
+	suite.mockCache.On("ConsumeOneTimeToken", emailRevertTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, nil)
+	suite.mockUserSaver.On("RestoreEmail", suite.ctx, suite.expectedUser.ID, suite.expectedUser.Email, mock.Anything).Return(nil)
+	suite.mockCache.On("RemoveUserSessions", suite.expectedUser.ID, []uuid.UUID(nil)).Return(nil)
+
+	err := suite.authService.RevertEmailChange(suite.ctx, "token")
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockCache.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_RevertEmailChange_UnknownToken() {
+	suite.mockCache.On("ConsumeOneTimeToken", emailRevertTokenKey("token")).Return(nil, domain_errors.ErrOneTimeTokenNotFound)
+
+	err := suite.authService.RevertEmailChange(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidEmailChangeToken)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "RestoreEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// IntrospectToken describes an access or refresh token following RFC 7662.
+// Tokens that are not valid are reported as inactive; an error means the
+// state of the token could not be determined.
+func (a *Auth) IntrospectToken(ctx context.Context, token string) (*models.Introspection, error) {
+	claims, err := a.ValidateToken(token, "")
+
+	switch {
+	case err == nil:
+		return a.introspectAccessToken(claims), nil
+	case errors.Is(err, domain_errors.ErrTokenMalformed):
+		// Refresh tokens are opaque, so they never parse as a JWT
+		return a.introspectRefreshToken(token)
+	case errors.Is(err, domain_errors.ErrInvalidToken):
+		return &models.Introspection{}, nil
+	default:
+		return nil, err
+	}
+}
+
+func (a *Auth) introspectAccessToken(claims *models.TokenClaims) *models.Introspection {
+	result := &models.Introspection{
+		Active:    true,
+		TokenType: TokenTypeAccess,
+		UserID:    claims.UserID,
+		TokenID:   claims.TokenID,
+		Audience:  claims.Audience,
+		Scope:     claims.Scope,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}
+
+	if len(claims.Audience) > 0 {
+		result.ClientID = claims.Audience[0]
+	}
+
+	if claims.SessionID != uuid.Nil {
+		session, err := a.redis.GetSession(claims.SessionID)
+		if err != nil {
+			// The token was already accepted, the session details are best effort
+			log.Printf("failed to load session: %v", err)
+		} else {
+			result.Session = session
+		}
+	}
+
+	return result
+}
+
+func (a *Auth) introspectRefreshToken(token string) (*models.Introspection, error) {
+	stored, err := a.redis.GetRefreshToken("refresh:" + opaque.Hash(token))
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrInvalidRefreshToken) {
+			return &models.Introspection{}, nil
+		}
+		return nil, fmt.Errorf("could not load refresh token: %w", err)
+	}
+
+	if stored.Rotated {
+		return &models.Introspection{}, nil
+	}
+
+	session, err := a.redis.GetSession(stored.SessionID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrSessionNotFound) {
+			return &models.Introspection{}, nil
+		}
+		return nil, fmt.Errorf("could not load session: %w", err)
+	}
+
+	return &models.Introspection{
+		Active:    true,
+		TokenType: TokenTypeRefresh,
+		UserID:    stored.UserID,
+		ClientID:  session.Audience,
+		Session:   session,
+	}, nil
+}
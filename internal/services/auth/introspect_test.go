@@ -0,0 +1,91 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
+	"errors"
+)
+
+func (suite *AuthTestSuite) TestAuth_IntrospectToken_AccessToken() {
+	claims := suite.validClaims()
+	claims.Audience = []string{"smap-mobile"}
+	claims.Scope = "profile"
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+
+	result, err := suite.authService.IntrospectToken(suite.ctx, "token")
+
+	suite.NoError(err)
+	suite.True(result.Active)
+	suite.Equal(TokenTypeAccess, result.TokenType)
+	suite.Equal(claims.UserID, result.UserID)
+	suite.Equal("smap-mobile", result.ClientID)
+	suite.Equal("profile", result.Scope)
+	suite.Equal(claims.ExpiresAt, result.ExpiresAt)
+	suite.Equal(claims.SessionID, result.Session.ID)
+}
+
+func (suite *AuthTestSuite) TestAuth_IntrospectToken_RevokedAccessToken() {
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(suite.validClaims(), nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(true, nil)
+
+	result, err := suite.authService.IntrospectToken(suite.ctx, "token")
+
+	suite.NoError(err)
+	suite.False(result.Active)
+}
+
+func (suite *AuthTestSuite) TestAuth_IntrospectToken_StoreUnavailable() {
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(suite.validClaims(), nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, errors.New("connection refused"))
+
+	result, err := suite.authService.IntrospectToken(suite.ctx, "token")
+
+	suite.Error(err)
+	suite.Nil(result)
+}
+
+func (suite *AuthTestSuite) TestAuth_IntrospectToken_RefreshToken() {
+	session := suite.storedSession()
+	suite.mockjwtService.On("ValidateToken", "refresh", "").Return(nil, domain_errors.ErrTokenMalformed)
+	suite.mockCache.On("GetRefreshToken", "refresh:"+opaque.Hash("refresh")).Return(&models.RefreshToken{
+		UserID:    suite.expectedUser.ID,
+		SessionID: session.ID,
+	}, nil)
+	suite.mockCache.On("GetSession", session.ID).Return(session, nil)
+
+	result, err := suite.authService.IntrospectToken(suite.ctx, "refresh")
+
+	suite.NoError(err)
+	suite.True(result.Active)
+	suite.Equal(TokenTypeRefresh, result.TokenType)
+	suite.Equal(suite.expectedUser.ID, result.UserID)
+	suite.Equal(session.Audience, result.ClientID)
+	suite.Equal(session, result.Session)
+}
+
+func (suite *AuthTestSuite) TestAuth_IntrospectToken_RotatedRefreshToken() {
+	suite.mockjwtService.On("ValidateToken", "refresh", "").Return(nil, domain_errors.ErrTokenMalformed)
+	suite.mockCache.On("GetRefreshToken", "refresh:"+opaque.Hash("refresh")).Return(&models.RefreshToken{
+		UserID:  suite.expectedUser.ID,
+		Rotated: true,
+	}, nil)
+
+	result, err := suite.authService.IntrospectToken(suite.ctx, "refresh")
+
+	suite.NoError(err)
+	suite.False(result.Active)
+	suite.mockCache.AssertNotCalled(suite.T(), "GetSession")
+}
+
+func (suite *AuthTestSuite) TestAuth_IntrospectToken_UnknownToken() {
+	suite.mockjwtService.On("ValidateToken", "unknown", "").Return(nil, domain_errors.ErrTokenMalformed)
+	suite.mockCache.On("GetRefreshToken", "refresh:"+opaque.Hash("unknown")).Return(nil, domain_errors.ErrInvalidRefreshToken)
+
+	result, err := suite.authService.IntrospectToken(suite.ctx, "unknown")
+
+	suite.NoError(err)
+	suite.False(result.Active)
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TopicAccountLocked          = "user-account-locked"
+	TopicAccountUnlockRequested = "user-account-unlock-requested"
+)
+
+// AccountLockedEvent is a security event about an account locked after too
+// many failed logins. The client is the one that made the last attempt.
+type AccountLockedEvent struct {
+	UserID      uuid.UUID `json:"user_id"`
+	LockedAt    time.Time `json:"locked_at"`
+	LockedUntil time.Time `json:"locked_until"`
+	ClientIP    string    `json:"client_ip"`
+	UserAgent   string    `json:"user_agent"`
+}
+
+// AccountUnlockRequestedEvent asks the mail service to send the unlock token
+// to the owner of the locked account
+type AccountUnlockRequestedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func unlockTokenKey(token string) string {
+	return "unlock_account:" + opaque.Hash(token)
+}
+
+// countFailedLogin records a wrong password for the account and locks it once
+// the configured number of consecutive failures is reached within the
+// window. This runs after the login returns, so that accounts that exist do
+// not answer slower than unknown ones, and the owner learns about the lock
+// by email. Failures while the account is locked are not counted, so that
+// they can not stretch the lock.
+func (a *Auth) countFailedLogin(user *models.User, client models.ClientInfo) {
+	if a.config.AccountLockoutThreshold <= 0 || user.Locked() {
+		return
+	}
+
+	a.runInBackground(func(ctx context.Context) {
+		a.lockAfterFailedLogin(ctx, user, client)
+	})
+}
+
+// lockAfterFailedLogin counts the failure and announces the lock when it
+// locked the account. The login has already failed, so errors are only
+// logged.
+func (a *Auth) lockAfterFailedLogin(ctx context.Context, user *models.User, client models.ClientInfo) {
+	now := time.Now()
+	lockedUntil := now.Add(a.config.AccountLockoutDuration)
+
+	locked, err := a.userSaver.RecordFailedLogin(
+		ctx,
+		user.ID,
+		now,
+		now.Add(-a.config.AccountLockoutWindow),
+		a.config.AccountLockoutThreshold,
+		lockedUntil,
+	)
+	if err != nil {
+		log.Printf("failed to record failed login of user %s: %v", user.ID, err)
+		return
+	}
+
+	if !locked {
+		return
+	}
+
+	a.publish(TopicAccountLocked, user.ID.String(), &AccountLockedEvent{
+		UserID:      user.ID,
+		LockedAt:    now,
+		LockedUntil: lockedUntil,
+		ClientIP:    client.IP,
+		UserAgent:   client.UserAgent,
+	})
+
+	// The lock runs out on its own, so a missing unlock email is not fatal
+	token, err := a.issueOneTimeToken(unlockTokenKey, user.ID, user.Email, a.config.AccountUnlockTokenTTL)
+	if err != nil {
+		log.Printf("failed to issue unlock token for user %s: %v", user.ID, err)
+		return
+	}
+
+	a.publish(TopicAccountUnlockRequested, user.ID.String(), &AccountUnlockRequestedEvent{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: now.Add(a.config.AccountUnlockTokenTTL),
+	})
+}
+
+// resetFailedLogins forgets the failures of an account that logged in
+func (a *Auth) resetFailedLogins(ctx context.Context, user *models.User) {
+	if user.FailedLogins == 0 {
+		return
+	}
+
+	if err := a.userSaver.ResetFailedLogins(ctx, user.ID); err != nil {
+		log.Printf("failed to reset failed logins of user %s: %v", user.ID, err)
+	}
+}
+
+// UnlockAccount consumes an unlock token mailed when the account was locked
+// and lifts the lock
+func (a *Auth) UnlockAccount(ctx context.Context, token string) error {
+	stored, err := a.redis.ConsumeOneTimeToken(unlockTokenKey(token))
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrOneTimeTokenNotFound) {
+			return domain_errors.ErrInvalidUnlockToken
+		}
+		return fmt.Errorf("could not load unlock token: %w", err)
+	}
+
+	user, err := a.userProvider.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidUnlockToken
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// The token went to an address the account no longer has
+	if user.Email != stored.Email {
+		return domain_errors.ErrInvalidUnlockToken
+	}
+
+	return a.unlock(ctx, user)
+}
+
+// UnlockUser lifts the lock of the account on behalf of an administrator
+func (a *Auth) UnlockUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := a.userProvider.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return a.unlock(ctx, user)
+}
+
+// unlock lifts the lock along with the login throttling of the account, so
+// that the owner can log in right away
+func (a *Auth) unlock(ctx context.Context, user *models.User) error {
+	if err := a.userSaver.UnlockUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+
+	a.resetAccountThrottle(user.ID)
+
+	return nil
+}
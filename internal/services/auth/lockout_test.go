@@ -0,0 +1,153 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// enableLockout locks accounts for an hour after 3 failures within 15 minutes
+func (suite *AuthTestSuite) enableLockout() {
+	suite.config.AccountLockoutThreshold = 3
+	suite.config.AccountLockoutWindow = 15 * time.Minute
+	suite.config.AccountLockoutDuration = time.Hour
+	suite.config.AccountUnlockTokenTTL = 24 * time.Hour
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_CountsFailure() {
+	suite.enableLockout()
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("RecordFailedLogin", suite.ctx, suite.expectedUser.ID, mock.Anything, mock.MatchedBy(func(windowStart time.Time) bool {
+		return time.Since(windowStart) >= 15*time.Minute
+	}), 3, mock.Anything).Return(false, nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "wrong password", models.ClientInfo{})
+	suite.authService.background.Wait()
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.Nil(tokens)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockKafka.AssertNotCalled(suite.T(), "Produce", mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_LocksAccount() {
+	suite.enableLockout()
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("RecordFailedLogin", suite.ctx, suite.expectedUser.ID, mock.Anything, mock.Anything, 3, mock.MatchedBy(func(lockedUntil time.Time) bool {
+		return time.Until(lockedUntil) > 59*time.Minute
+	})).Return(true, nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.AnythingOfType("string"), &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, 24*time.Hour).Return(nil)
+	produced := suite.expectProduce()
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "wrong password", models.ClientInfo{IP: "10.0.0.1"})
+	suite.authService.background.Wait()
+
+	// The owner is told by email, the caller only sees a wrong password
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.Nil(tokens)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.ElementsMatch([]string{TopicAccountLocked, TopicAccountUnlockRequested}, suite.receive(produced, 2))
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_LockedAccount() {
+	suite.enableLockout()
+	hasher := &spyHasher{PasswordHasher: suite.authService.hasher}
+	suite.authService.hasher = hasher
+	suite.expectedUser.LockedUntil = time.Now().Add(time.Hour)
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserProvider.On("GetUser", suite.ctx, "nobody@test.com").Return(nil, domain_errors.ErrUserNotFound)
+
+	_, rightErr := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+	_, wrongErr := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "wrong password", models.ClientInfo{})
+	_, unknownErr := suite.authService.Login(suite.ctx, "nobody@test.com", "password", models.ClientInfo{})
+	suite.authService.background.Wait()
+
+	// The right password can not be told from a wrong one, nor the account
+	// from an unknown one
+	suite.ErrorIs(rightErr, domain_errors.ErrInvalidCredentials)
+	suite.Equal(unknownErr, rightErr)
+	suite.Equal(unknownErr, wrongErr)
+	suite.Len(hasher.compared, 3)
+	suite.NotContains(hasher.compared, suite.expectedUser.PassHash)
+	suite.mockCache.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_LockExpired() {
+	suite.enableLockout()
+	suite.expectedUser.LockedUntil = time.Now().Add(-time.Minute)
+	suite.expectedUser.FailedLogins = 2
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+	suite.mockUserSaver.On("ResetFailedLogins", suite.ctx, suite.expectedUser.ID).Return(nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_UnlockAccount_Success() {
+	suite.mockCache.On("ConsumeOneTimeToken", unlockTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("UnlockUser", suite.ctx, suite.expectedUser.ID).Return(nil)
+
+	err := suite.authService.UnlockAccount(suite.ctx, "token")
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_UnlockAccount_UnknownToken() {
+	suite.mockCache.On("ConsumeOneTimeToken", unlockTokenKey("token")).Return(nil, domain_errors.ErrOneTimeTokenNotFound)
+
+	err := suite.authService.UnlockAccount(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidUnlockToken)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "UnlockUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_UnlockAccount_AddressChanged() {
+	suite.mockCache.On("ConsumeOneTimeToken", unlockTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "old@test.com",
+	}, nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.UnlockAccount(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidUnlockToken)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "UnlockUser", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_UnlockUser_ResetsThrottling() {
+	suite.enableLoginThrottling()
+	suite.expectedUser.Username = "JDoe"
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("UnlockUser", suite.ctx, suite.expectedUser.ID).Return(nil)
+	suite.mockCache.On("ResetLoginFailures", accountThrottleKey(suite.expectedUser.ID)).Return(nil)
+
+	err := suite.authService.UnlockUser(suite.ctx, suite.expectedUser.ID)
+
+	suite.NoError(err)
+	suite.mockCache.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_UnlockUser_UnknownUser() {
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(nil, domain_errors.ErrUserNotFound)
+
+	err := suite.authService.UnlockUser(suite.ctx, suite.expectedUser.ID)
+
+	suite.ErrorIs(err, domain_errors.ErrUserNotFound)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "UnlockUser", mock.Anything, mock.Anything)
+}
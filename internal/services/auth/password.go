@@ -0,0 +1,222 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const TopicPasswordChanged = "password-changed"
+
+// PasswordChangedEvent lets the user know the password was changed
+type PasswordChangedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ChangePassword sets a new password for the token owner once the current
+// one is confirmed, and ends every other session of the user. Wrong current
+// passwords are throttled and lock the account the same way failed logins do.
+func (a *Auth) ChangePassword(
+	ctx context.Context,
+	token string,
+	currentPassword string,
+	newPassword string,
+	client models.ClientInfo,
+) error {
+	claims, err := a.ValidateToken(token, "")
+	if err != nil {
+		return err
+	}
+
+	user, err := a.userProvider.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidToken
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if _, err = a.checkPasswordAttempt(user, user.Email, currentPassword, client); err != nil {
+		return err
+	}
+
+	if err = a.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	if err = a.redis.RemoveUserSessions(user.ID, claims.SessionID); err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+
+	a.resetAccountThrottle(user.ID)
+	a.resetFailedLogins(ctx, user)
+
+	a.publish(TopicPasswordChanged, user.ID.String(), &PasswordChangedEvent{
+		UserID:    user.ID,
+		Email:     user.Email,
+		ChangedAt: time.Now(),
+	})
+
+	return nil
+}
+
+// setPassword checks the password against the policy and the recent
+// passwords of the user, and stores its hash
+func (a *Auth) setPassword(ctx context.Context, user *models.User, password string) error {
+	if err := a.checkNewPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	return a.storePassword(ctx, user, password)
+}
+
+// checkNewPassword rejects a password that breaks the policy or was used
+// recently by the user
+func (a *Auth) checkNewPassword(ctx context.Context, user *models.User, password string) error {
+	if _, err := a.CheckPasswordStrength(password, user.Email, user.Username); err != nil {
+		return err
+	}
+
+	return a.checkPasswordReuse(ctx, user, password, a.passwordHistoryCutoff())
+}
+
+// storePassword stores the hash of a checked password and moves the
+// previous one into the history
+func (a *Auth) storePassword(ctx context.Context, user *models.User, password string) error {
+	retiredAfter := a.passwordHistoryCutoff()
+
+	passHash, pepperVersion, err := a.hashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to generate password hash: %w", err)
+	}
+
+	err = a.userSaver.SetPassword(ctx, user.ID, passHash, pepperVersion, a.config.PasswordHistorySize, retiredAfter)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return nil
+}
+
+// passwordHistoryCutoff returns the time before which retired passwords are
+// forgotten, zero when they are kept regardless of age
+func (a *Auth) passwordHistoryCutoff() time.Time {
+	if a.config.PasswordHistoryRetention <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(-a.config.PasswordHistoryRetention)
+}
+
+// checkPasswordReuse rejects the current password of the user and the ones
+// kept in the history. It is a no-op when the history is disabled.
+func (a *Auth) checkPasswordReuse(ctx context.Context, user *models.User, password string, retiredAfter time.Time) error {
+	if a.config.PasswordHistorySize <= 0 {
+		return nil
+	}
+
+	history, err := a.userProvider.GetPasswordHistory(ctx, user.ID, a.config.PasswordHistorySize, retiredAfter)
+	if err != nil {
+		return fmt.Errorf("failed to get password history: %w", err)
+	}
+
+	current := models.PasswordHash{Hash: user.PassHash, PepperVersion: user.PepperVersion}
+	for _, passHash := range append([]models.PasswordHash{current}, history...) {
+		match, _, err := a.comparePassword(password, passHash.Hash, passHash.PepperVersion)
+		if err != nil {
+			// A hash that can not be read must not lock the user out of changing the password
+			log.Printf("failed to compare with previous password of user %s: %v", user.ID, err)
+			continue
+		}
+
+		if match {
+			return &domain_errors.PasswordPolicyError{Violations: []error{domain_errors.ErrPasswordReused}}
+		}
+	}
+
+	return nil
+}
+
+// hashPassword peppers the password with the current pepper and hashes it.
+// It returns the hash along with the pepper version to store.
+func (a *Auth) hashPassword(password string) ([]byte, int, error) {
+	version := a.peppers.Current()
+
+	peppered, err := a.peppers.Apply(password, version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	passHash, err := a.hasher.Hash(peppered)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return passHash, version, nil
+}
+
+// comparePassword checks the password against a hash made with the given
+// pepper version. A hash is outdated when either its hashing settings or its
+// pepper are not the current ones.
+func (a *Auth) comparePassword(password string, passHash []byte, pepperVersion int) (bool, bool, error) {
+	peppered, err := a.peppers.Apply(password, pepperVersion)
+	if err != nil {
+		return false, false, err
+	}
+
+	match, needsRehash, err := a.hasher.Verify(peppered, passHash)
+	if err != nil {
+		return false, false, err
+	}
+
+	return match, needsRehash || pepperVersion != a.peppers.Current(), nil
+}
+
+// compareDummyPassword takes as long as checking the password of an account,
+// so that unknown identifiers can not be told apart from wrong passwords by
+// the response time
+func (a *Auth) compareDummyPassword(password string) {
+	_, _, _ = a.comparePassword(password, a.dummyPassHash(), a.peppers.Current())
+}
+
+// verifyPassword checks the password of the user and reports whether the
+// stored hash should be upgraded to the configured hashing settings and pepper
+func (a *Auth) verifyPassword(user *models.User, password string) (bool, error) {
+	match, needsRehash, err := a.comparePassword(password, user.PassHash, user.PepperVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify password: %w", err)
+	}
+
+	if !match {
+		return false, domain_errors.ErrInvalidCredentials
+	}
+
+	return needsRehash, nil
+}
+
+// rehashPassword replaces the stored hash of a password that was just
+// verified. A failure leaves the old hash in place, which still verifies as
+// long as its pepper is configured.
+func (a *Auth) rehashPassword(ctx context.Context, user *models.User, password string) {
+	passHash, pepperVersion, err := a.hashPassword(password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %s: %v", user.ID, err)
+		return
+	}
+
+	if err = a.userSaver.UpdatePassword(ctx, user.ID, passHash, pepperVersion); err != nil {
+		log.Printf("failed to store rehashed password of user %s: %v", user.ID, err)
+		return
+	}
+
+	user.PassHash = passHash
+	user.PepperVersion = pepperVersion
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	passwordClassLower  = "lower"
+	passwordClassUpper  = "upper"
+	passwordClassDigit  = "digit"
+	passwordClassSymbol = "symbol"
+)
+
+var passwordClassNames = map[string]string{
+	passwordClassLower:  "a lowercase letter",
+	passwordClassUpper:  "an uppercase letter",
+	passwordClassDigit:  "a digit",
+	passwordClassSymbol: "a symbol",
+}
+
+// minPersonalInfoLength keeps very short usernames from ruling out common passwords
+const minPersonalInfoLength = 3
+
+// CheckPasswordStrength estimates the strength of the password in bits and
+// checks it against the configured policy and the breached passwords, when
+// a dataset is configured. The email and username are the
+// ones of the account the password is for and may be empty. A rejected
+// password yields a PasswordPolicyError listing every rule it breaks.
+func (a *Auth) CheckPasswordStrength(password string, email string, username string) (float64, error) {
+	var violations []error
+
+	length := utf8.RuneCountInString(password)
+	if length < a.config.PasswordMinLength {
+		violations = append(violations, fmt.Errorf("%w: at least %d characters are required", domain_errors.ErrPasswordTooShort, a.config.PasswordMinLength))
+	}
+	if a.config.PasswordMaxLength > 0 && length > a.config.PasswordMaxLength {
+		violations = append(violations, fmt.Errorf("%w: at most %d characters are allowed", domain_errors.ErrPasswordTooLong, a.config.PasswordMaxLength))
+	}
+
+	classes := passwordClasses(password)
+	for _, class := range a.config.PasswordRequiredClasses {
+		if !classes[class] {
+			violations = append(violations, fmt.Errorf("%w: add %s", domain_errors.ErrPasswordMissingCharacterClass, passwordClassNames[class]))
+		}
+	}
+
+	entropy := estimateEntropy(password)
+	if entropy < float64(a.config.PasswordMinEntropyBits) {
+		violations = append(violations, fmt.Errorf("%w: add more characters or vary them", domain_errors.ErrPasswordTooWeak))
+	}
+
+	if containsPersonalInfo(password, email, username) {
+		violations = append(violations, domain_errors.ErrPasswordContainsPersonalInfo)
+	}
+
+	if a.breaches != nil {
+		breached, err := a.breaches.Contains(password)
+		if err != nil {
+			return entropy, fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+
+		if breached {
+			violations = append(violations, domain_errors.ErrPasswordBreached)
+		}
+	}
+
+	if len(violations) > 0 {
+		return entropy, &domain_errors.PasswordPolicyError{Violations: violations}
+	}
+
+	return entropy, nil
+}
+
+// passwordClasses returns the character classes the password uses
+func passwordClasses(password string) map[string]bool {
+	classes := make(map[string]bool, len(passwordClassNames))
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			classes[passwordClassLower] = true
+		case unicode.IsUpper(r):
+			classes[passwordClassUpper] = true
+		case unicode.IsDigit(r):
+			classes[passwordClassDigit] = true
+		default:
+			classes[passwordClassSymbol] = true
+		}
+	}
+
+	return classes
+}
+
+// estimateEntropy returns a rough strength estimate in bits, assuming every
+// character is drawn from the union of the alphabets the password uses.
+// Characters repeating or continuing a sequence, as in "aaa" or "abc",
+// count for a quarter.
+func estimateEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	var length float64
+	var previous rune = -1
+
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+
+		if previous >= 0 && r-previous >= -1 && r-previous <= 1 {
+			length += 0.25
+		} else {
+			length++
+		}
+		previous = r
+	}
+
+	pool := 0
+	for _, alphabet := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if alphabet.used {
+			pool += alphabet.size
+		}
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return length * math.Log2(float64(pool))
+}
+
+// containsPersonalInfo reports whether the password includes the username or
+// the local part of the email, ignoring case
+func containsPersonalInfo(password string, email string, username string) bool {
+	password = strings.ToLower(password)
+	localPart, _, _ := strings.Cut(email, "@")
+
+	for _, info := range []string{username, localPart} {
+		if utf8.RuneCountInString(info) >= minPersonalInfoLength && strings.Contains(password, strings.ToLower(info)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"errors"
+	"strings"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AuthTestSuite) TestAuth_CheckPasswordStrength_Accepted() {
+	entropy, err := suite.authService.CheckPasswordStrength("correct horse battery", suite.expectedUser.Email, "JDoe")
+
+	suite.NoError(err)
+	suite.Greater(entropy, 30.0)
+}
+
+func (suite *AuthTestSuite) TestAuth_CheckPasswordStrength_Violations() {
+	suite.config.PasswordRequiredClasses = []string{passwordClassUpper, passwordClassDigit}
+
+	cases := []struct {
+		name       string
+		password   string
+		violations []error
+	}{
+		{"too short", "Xk9#mQ2", []error{domain_errors.ErrPasswordTooShort}},
+		{"too long", "Xk9" + strings.Repeat("ab", 70), []error{domain_errors.ErrPasswordTooLong}},
+		{"missing classes", "correct horse battery", []error{
+			domain_errors.ErrPasswordMissingCharacterClass,
+			domain_errors.ErrPasswordMissingCharacterClass,
+		}},
+		{"repeated characters", "AAAAAAAAAA11", []error{domain_errors.ErrPasswordTooWeak}},
+		{"sequence", "ABCDEFGH1234", []error{domain_errors.ErrPasswordTooWeak}},
+		{"contains username", "xX-jdoe-Xx-42", []error{domain_errors.ErrPasswordContainsPersonalInfo}},
+		{"contains email", "John_Doe-1984!", []error{domain_errors.ErrPasswordContainsPersonalInfo}},
+	}
+
+	for _, c := range cases {
+		suite.Run(c.name, func() {
+			_, err := suite.authService.CheckPasswordStrength(c.password, suite.expectedUser.Email, "JDoe")
+
+			var policyErr *domain_errors.PasswordPolicyError
+			suite.Require().ErrorAs(err, &policyErr)
+			suite.Require().Len(policyErr.Violations, len(c.violations))
+			for i, violation := range c.violations {
+				suite.ErrorIs(policyErr.Violations[i], violation)
+			}
+		})
+	}
+}
+
+func (suite *AuthTestSuite) TestAuth_CheckPasswordStrength_ShortPersonalInfoIgnored() {
+	_, err := suite.authService.CheckPasswordStrength("jo-correct-horse", "jo@test.com", "jo")
+
+	suite.NoError(err)
+}
+
+func (suite *AuthTestSuite) TestEstimateEntropy() {
+	suite.Zero(estimateEntropy(""))
+	suite.Less(estimateEntropy("aaaaaaaa"), estimateEntropy("password"))
+	suite.Less(estimateEntropy("12345678"), estimateEntropy("31415926"))
+	suite.Less(estimateEntropy("password"), estimateEntropy("Password1!"))
+}
+
+// breachedPasswords stands in for a breach dataset
+type breachedPasswords map[string]bool
+
+func (b breachedPasswords) Contains(password string) (bool, error) {
+	return b[password], nil
+}
+
+func (suite *AuthTestSuite) TestAuth_CheckPasswordStrength_Breached() {
+	suite.authService.breaches = breachedPasswords{"correct horse battery": true}
+
+	_, err := suite.authService.CheckPasswordStrength("correct horse battery", suite.expectedUser.Email, "JDoe")
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordBreached)
+}
+
+// unreadableBreaches fails every lookup, as a broken dataset would
+type unreadableBreaches struct{}
+
+func (unreadableBreaches) Contains(string) (bool, error) {
+	return false, errors.New("input/output error")
+}
+
+func (suite *AuthTestSuite) TestAuth_CheckPasswordStrength_BreachLookupFails() {
+	suite.authService.breaches = unreadableBreaches{}
+
+	_, err := suite.authService.CheckPasswordStrength("correct horse battery", suite.expectedUser.Email, "JDoe")
+
+	var policyErr *domain_errors.PasswordPolicyError
+	suite.Error(err)
+	suite.NotErrorAs(err, &policyErr)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_Breached() {
+	suite.authService.breaches = breachedPasswords{"correct horse battery": true}
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "correct horse battery", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordBreached)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const TopicPasswordResetRequested = "user-password-reset-requested"
+
+// PasswordResetRequestedEvent asks the mail service to send the reset token to the user
+type PasswordResetRequestedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func passwordResetTokenKey(token string) string {
+	return "reset_password:" + opaque.Hash(token)
+}
+
+// RequestPasswordReset mails a single-use reset token to the account with
+// this email. Unknown addresses are silently ignored. The account is looked
+// up after the call returns, so that neither the result nor the response
+// time tells which accounts exist.
+func (a *Auth) RequestPasswordReset(ctx context.Context, email string) error {
+	a.runInBackground(func(ctx context.Context) {
+		if err := a.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("failed to send password reset: %v", err)
+		}
+	})
+
+	return nil
+}
+
+// sendPasswordReset stores a reset token for the account with this email and
+// asks the mail service to deliver it
+func (a *Auth) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := a.userProvider.GetUser(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := a.issueOneTimeToken(passwordResetTokenKey, user.ID, user.Email, a.config.PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	a.publish(TopicPasswordResetRequested, user.ID.String(), &PasswordResetRequestedEvent{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: time.Now().Add(a.config.PasswordResetTokenTTL),
+	})
+
+	return nil
+}
+
+// ConfirmPasswordReset sets the new password of the reset token owner and
+// ends every session of the user. The token is only consumed once the
+// password is accepted, so that a rejected one does not waste the link.
+func (a *Auth) ConfirmPasswordReset(ctx context.Context, token string, password string) error {
+	key := passwordResetTokenKey(token)
+
+	stored, err := a.redis.GetOneTimeToken(key)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrOneTimeTokenNotFound) {
+			return domain_errors.ErrInvalidPasswordResetToken
+		}
+		return fmt.Errorf("could not load password reset token: %w", err)
+	}
+
+	user, err := a.userProvider.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidPasswordResetToken
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// The token went to an address the account no longer has
+	if user.Email != stored.Email {
+		return domain_errors.ErrInvalidPasswordResetToken
+	}
+
+	if err = a.checkNewPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	// Of concurrent confirmations only the one consuming the token goes on
+	if _, err = a.redis.ConsumeOneTimeToken(key); err != nil {
+		if errors.Is(err, domain_errors.ErrOneTimeTokenNotFound) {
+			return domain_errors.ErrInvalidPasswordResetToken
+		}
+		return fmt.Errorf("could not consume password reset token: %w", err)
+	}
+
+	if err = a.storePassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	if err = a.redis.RemoveUserSessions(user.ID); err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/pepper"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (suite *AuthTestSuite) TestAuth_RequestPasswordReset_Success() {
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.AnythingOfType("string"), &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, 30*time.Minute).Return(nil)
+	produced := suite.expectProduce()
+
+	err := suite.authService.RequestPasswordReset(suite.ctx, suite.expectedUser.Email)
+	suite.authService.background.Wait()
+
+	suite.NoError(err)
+
+	var event PasswordResetRequestedEvent
+	msg := <-produced
+	suite.Equal(TopicPasswordResetRequested, msg.Topic)
+	suite.NoError(json.Unmarshal(msg.Value, &event))
+	suite.Equal(suite.expectedUser.ID, event.UserID)
+	suite.mockCache.AssertCalled(suite.T(), "StoreOneTimeToken", passwordResetTokenKey(event.Token), mock.Anything, 30*time.Minute)
+}
+
+func (suite *AuthTestSuite) TestAuth_RequestPasswordReset_UnknownEmail() {
+	suite.mockUserProvider.On("GetUser", suite.ctx, "nobody@test.com").Return(nil, domain_errors.ErrUserNotFound)
+
+	err := suite.authService.RequestPasswordReset(suite.ctx, "nobody@test.com")
+	suite.authService.background.Wait()
+
+	suite.NoError(err)
+	suite.mockCache.AssertNotCalled(suite.T(), "StoreOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockKafka.AssertNotCalled(suite.T(), "Produce", mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmPasswordReset_Success() {
+	stored := &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}
+	suite.mockCache.On("GetOneTimeToken", passwordResetTokenKey("token")).Return(stored, nil)
+	suite.mockCache.On("ConsumeOneTimeToken", passwordResetTokenKey("token")).Return(stored, nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserProvider.On("GetPasswordHistory", suite.ctx, suite.expectedUser.ID, 5, mock.Anything).Return(nil, nil)
+	suite.mockUserSaver.On("SetPassword", suite.ctx, suite.expectedUser.ID, mock.MatchedBy(func(passHash []byte) bool {
+		return bcrypt.CompareHashAndPassword(passHash, []byte("new password")) == nil
+	}), pepper.VersionNone, 5, mock.Anything).Return(nil)
+	suite.mockCache.On("RemoveUserSessions", suite.expectedUser.ID, []uuid.UUID(nil)).Return(nil)
+
+	err := suite.authService.ConfirmPasswordReset(suite.ctx, "token", "new password")
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockCache.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmPasswordReset_UnknownToken() {
+	suite.mockCache.On("GetOneTimeToken", passwordResetTokenKey("token")).Return(nil, domain_errors.ErrOneTimeTokenNotFound)
+
+	err := suite.authService.ConfirmPasswordReset(suite.ctx, "token", "new password")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidPasswordResetToken)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmPasswordReset_AddressChanged() {
+	suite.mockCache.On("GetOneTimeToken", passwordResetTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "old@test.com",
+	}, nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.ConfirmPasswordReset(suite.ctx, "token", "new password")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidPasswordResetToken)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.mockCache.AssertNotCalled(suite.T(), "RemoveUserSessions", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmPasswordReset_PolicyViolationKeepsToken() {
+	suite.mockCache.On("GetOneTimeToken", passwordResetTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.ConfirmPasswordReset(suite.ctx, "token", "short")
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordTooShort)
+	suite.mockCache.AssertNotCalled(suite.T(), "ConsumeOneTimeToken", mock.Anything)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ConfirmPasswordReset_ConsumedConcurrently() {
+	suite.mockCache.On("GetOneTimeToken", passwordResetTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserProvider.On("GetPasswordHistory", suite.ctx, suite.expectedUser.ID, 5, mock.Anything).Return(nil, nil)
+	suite.mockCache.On("ConsumeOneTimeToken", passwordResetTokenKey("token")).Return(nil, domain_errors.ErrOneTimeTokenNotFound)
+
+	err := suite.authService.ConfirmPasswordReset(suite.ctx, "token", "new password")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidPasswordResetToken)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_RequestPasswordReset_ReturnsBeforeLookup() {
+	lookup := make(chan struct{})
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Run(func(mock.Arguments) {
+		<-lookup
+	}).Return(nil, domain_errors.ErrUserNotFound)
+
+	err := suite.authService.RequestPasswordReset(suite.ctx, suite.expectedUser.Email)
+
+	suite.NoError(err)
+	close(lookup)
+	suite.authService.background.Wait()
+	suite.mockUserProvider.AssertExpectations(suite.T())
+}
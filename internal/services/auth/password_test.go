@@ -0,0 +1,319 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/passhash"
+	"auth-service/internal/lib/pepper"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_Success() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+	suite.mockUserProvider.On("GetPasswordHistory", suite.ctx, suite.expectedUser.ID, 5, mock.Anything).Return(nil, nil)
+	suite.mockUserSaver.On("SetPassword", suite.ctx, suite.expectedUser.ID, mock.MatchedBy(func(passHash []byte) bool {
+		return bcrypt.CompareHashAndPassword(passHash, []byte("new password")) == nil
+	}), pepper.VersionNone, 5, mock.Anything).Return(nil)
+	suite.mockCache.On("RemoveUserSessions", suite.expectedUser.ID, []uuid.UUID{claims.SessionID}).Return(nil)
+	produced := suite.expectProduce()
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "new password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockCache.AssertExpectations(suite.T())
+
+	var event PasswordChangedEvent
+	msg := <-produced
+	suite.Equal(TopicPasswordChanged, msg.Topic)
+	suite.NoError(json.Unmarshal(msg.Value, &event))
+	suite.Equal(suite.expectedUser.ID, event.UserID)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_WrongCurrentPassword() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "wrong_password", "new password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.mockCache.AssertNotCalled(suite.T(), "RemoveUserSessions", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_WrongCurrentPasswordCounts() {
+	suite.enableLoginThrottling()
+	suite.enableLockout()
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	suite.expectChangePasswordCaller()
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "10.0.0.1", 0, nil)
+	suite.mockUserSaver.On("RecordFailedLogin", suite.ctx, suite.expectedUser.ID, mock.Anything, mock.Anything, 3, mock.Anything).Return(false, nil)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "wrong_password", "new password", client)
+	suite.authService.background.Wait()
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.mockUserSaver.AssertExpectations(suite.T())
+	suite.mockCache.AssertNotCalled(suite.T(), "ReleaseLoginAttempt", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_Throttled() {
+	suite.enableLoginThrottling()
+	suite.expectChangePasswordCaller()
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "", 4*time.Second, nil)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "new password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrTooManyLoginAttempts)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_Locked() {
+	suite.enableLockout()
+	suite.expectedUser.LockedUntil = time.Now().Add(time.Hour)
+	hasher := &spyHasher{PasswordHasher: suite.authService.hasher}
+	suite.authService.hasher = hasher
+	suite.expectChangePasswordCaller()
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "new password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.Len(hasher.compared, 1)
+	suite.NotContains(hasher.compared, suite.expectedUser.PassHash)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_PolicyViolation() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "short", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordTooShort)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_InvalidToken() {
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(nil, domain_errors.ErrTokenExpired)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "new password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrTokenExpired)
+	suite.mockUserProvider.AssertNotCalled(suite.T(), "GetUserByID", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Register_PasswordTooShort() {
+	uid, err := suite.authService.Register(suite.ctx, suite.expectedUser.Email, "JDoe", "short")
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordTooShort)
+	suite.Equal(uuid.Nil, uid)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SaveUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_RehashesOutdatedHash() {
+	hasher, err := passhash.New(passhash.AlgorithmArgon2id, passhash.Argon2Params{
+		Memory:      64,
+		Iterations:  1,
+		Parallelism: 1,
+	}, bcrypt.DefaultCost)
+	suite.Require().NoError(err)
+	suite.authService.hasher = hasher
+
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("UpdatePassword", suite.ctx, suite.expectedUser.ID, mock.MatchedBy(func(passHash []byte) bool {
+		match, needsRehash, err := hasher.Verify("password", passHash)
+		return err == nil && match && !needsRehash
+	}), pepper.VersionNone).Return(nil)
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_RehashFailureDoesNotFailLogin() {
+	hasher, err := passhash.New(passhash.AlgorithmBcrypt, passhash.Argon2Params{}, bcrypt.MinCost)
+	suite.Require().NoError(err)
+	suite.authService.hasher = hasher
+
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("UpdatePassword", suite.ctx, suite.expectedUser.ID, mock.Anything, pepper.VersionNone).Return(errors.New("connection refused"))
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_ReusesCurrentPassword() {
+	suite.expectChangePasswordCaller()
+	suite.mockUserProvider.On("GetPasswordHistory", suite.ctx, suite.expectedUser.ID, 5, mock.Anything).Return(nil, nil)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordReused)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_ReusesPreviousPassword() {
+	previous, err := bcrypt.GenerateFromPassword([]byte("old password"), bcrypt.MinCost)
+	suite.Require().NoError(err)
+
+	suite.expectChangePasswordCaller()
+	suite.mockUserProvider.On("GetPasswordHistory", suite.ctx, suite.expectedUser.ID, 5, mock.MatchedBy(func(retiredAfter time.Time) bool {
+		return time.Since(retiredAfter) > 364*24*time.Hour
+	})).Return([]models.PasswordHash{{Hash: []byte("not a hash")}, {Hash: previous}}, nil)
+
+	err = suite.authService.ChangePassword(suite.ctx, "token", "password", "old password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordReused)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_HistoryDisabled() {
+	suite.config.PasswordHistorySize = 0
+	suite.expectChangePasswordCaller()
+	suite.mockUserSaver.On("SetPassword", suite.ctx, suite.expectedUser.ID, mock.Anything, pepper.VersionNone, 0, mock.Anything).Return(nil)
+	suite.mockCache.On("RemoveUserSessions", suite.expectedUser.ID, mock.Anything).Return(nil)
+	suite.mockKafka.On("Produce", mock.Anything).Return(nil)
+
+	err := suite.authService.ChangePassword(suite.ctx, "token", "password", "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.mockUserProvider.AssertNotCalled(suite.T(), "GetPasswordHistory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// expectChangePasswordCaller stubs a valid access token of the test user
+func (suite *AuthTestSuite) expectChangePasswordCaller() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+}
+
+// usePeppers switches the service to the given peppers
+func (suite *AuthTestSuite) usePeppers(secrets map[int][]byte, current int) *pepper.Peppers {
+	peppers, err := pepper.New(secrets, current)
+	suite.Require().NoError(err)
+	suite.authService.peppers = peppers
+
+	return peppers
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_RepeppersOutdatedHash() {
+	peppers := suite.usePeppers(map[int][]byte{1: []byte("secret")}, 1)
+
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserSaver.On("UpdatePassword", suite.ctx, suite.expectedUser.ID, mock.MatchedBy(func(passHash []byte) bool {
+		peppered, err := peppers.Apply("password", 1)
+		return err == nil && bcrypt.CompareHashAndPassword(passHash, []byte(peppered)) == nil
+	}), 1).Return(nil)
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+	suite.Equal(1, suite.expectedUser.PepperVersion)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_PepperedHash() {
+	peppers := suite.usePeppers(map[int][]byte{1: []byte("old secret"), 2: []byte("new secret")}, 2)
+	peppered, err := peppers.Apply("password", 2)
+	suite.Require().NoError(err)
+	suite.expectedUser.PassHash, err = bcrypt.GenerateFromPassword([]byte(peppered), bcrypt.DefaultCost)
+	suite.Require().NoError(err)
+	suite.expectedUser.PepperVersion = 2
+
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "UpdatePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_WrongPepper() {
+	suite.usePeppers(map[int][]byte{1: []byte("secret")}, 1)
+	suite.expectedUser.PepperVersion = 1
+
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.Nil(tokens)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_RemovedPepper() {
+	suite.usePeppers(map[int][]byte{2: []byte("secret")}, 2)
+	suite.expectedUser.PepperVersion = 1
+
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, pepper.ErrUnknownVersion)
+	suite.Nil(tokens)
+	suite.mockCache.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Register_StoresPepperVersion() {
+	peppers := suite.usePeppers(map[int][]byte{3: []byte("secret")}, 3)
+	suite.mockUserSaver.On("SaveUser", suite.ctx, suite.expectedUser.Email, "JDoe", mock.MatchedBy(func(passHash string) bool {
+		peppered, err := peppers.Apply("password", 3)
+		return err == nil && bcrypt.CompareHashAndPassword([]byte(passHash), []byte(peppered)) == nil
+	}), 3).Return(suite.expectedUser.ID, nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.expectProduce()
+
+	uid, err := suite.authService.Register(suite.ctx, suite.expectedUser.Email, "JDoe", "password")
+
+	suite.NoError(err)
+	suite.Equal(suite.expectedUser.ID, uid)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_ChangePassword_ReusesPepperedPassword() {
+	peppers := suite.usePeppers(map[int][]byte{1: []byte("old secret"), 2: []byte("new secret")}, 2)
+	peppered, err := peppers.Apply("old password", 1)
+	suite.Require().NoError(err)
+	previous, err := bcrypt.GenerateFromPassword([]byte(peppered), bcrypt.MinCost)
+	suite.Require().NoError(err)
+
+	suite.expectChangePasswordCaller()
+	suite.mockUserProvider.On("GetPasswordHistory", suite.ctx, suite.expectedUser.ID, 5, mock.Anything).
+		Return([]models.PasswordHash{{Hash: previous, PepperVersion: 1}}, nil)
+
+	err = suite.authService.ChangePassword(suite.ctx, "token", "password", "old password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrPasswordReused)
+}
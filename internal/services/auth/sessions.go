@@ -0,0 +1,125 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sessionTouchInterval limits how often token validation records session activity
+const sessionTouchInterval = time.Minute
+
+// isSessionActive reports whether the session the token was issued for still
+// exists. Tokens issued before sessions were introduced carry no session id.
+func (a *Auth) isSessionActive(claims *models.TokenClaims) (bool, error) {
+	if claims.SessionID == uuid.Nil {
+		return true, nil
+	}
+
+	session, err := a.redis.GetSession(claims.SessionID)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrSessionNotFound) {
+			return false, nil
+		}
+
+		if a.config.RevocationFailOpen {
+			log.Printf("failed to load session: %v", err)
+			return true, nil
+		}
+		return false, fmt.Errorf("could not load session: %w", err)
+	}
+
+	if session.UserID != claims.UserID {
+		return false, nil
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		session.LastSeenAt = time.Now()
+		if err = a.redis.TouchSession(session, 0); err != nil {
+			// The session was revoked after it was read
+			if errors.Is(err, domain_errors.ErrSessionNotFound) {
+				return false, nil
+			}
+			log.Printf("failed to update session: %v", err)
+		}
+	}
+
+	return true, nil
+}
+
+// ListSessions returns the sessions of the token owner and marks the one the token belongs to
+func (a *Auth) ListSessions(ctx context.Context, token string) ([]*models.Session, error) {
+	claims, err := a.ValidateToken(token, "")
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := a.ListUserSessions(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == claims.SessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends one of the sessions of the token owner
+func (a *Auth) RevokeSession(ctx context.Context, token string, sessionID uuid.UUID) error {
+	claims, err := a.ValidateToken(token, "")
+	if err != nil {
+		return err
+	}
+
+	return a.RevokeUserSession(ctx, claims.UserID, sessionID)
+}
+
+// RevokeAllSessions ends every session of the token owner, including the current one
+func (a *Auth) RevokeAllSessions(ctx context.Context, token string) error {
+	claims, err := a.ValidateToken(token, "")
+	if err != nil {
+		return err
+	}
+
+	return a.RevokeUserSessions(ctx, claims.UserID)
+}
+
+// ListUserSessions returns the sessions of the user, most recently used first
+func (a *Auth) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	sessions, err := a.redis.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+func (a *Auth) RevokeUserSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	session, err := a.redis.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID {
+		return domain_errors.ErrSessionNotFound
+	}
+
+	return a.redis.RemoveSession(userID, sessionID)
+}
+
+func (a *Auth) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	return a.redis.RemoveUserSessions(userID)
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AuthTestSuite) TestAuth_ListSessions_MarksCurrent() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+
+	other := &models.Session{ID: uuid.New(), UserID: claims.UserID, LastSeenAt: time.Now().Add(-time.Hour)}
+	current := &models.Session{ID: claims.SessionID, UserID: claims.UserID, LastSeenAt: time.Now()}
+	suite.mockCache.On("ListSessions", claims.UserID).Return([]*models.Session{other, current}, nil)
+
+	sessions, err := suite.authService.ListSessions(suite.ctx, "token")
+
+	suite.NoError(err)
+	suite.Len(sessions, 2)
+	suite.Equal(current.ID, sessions[0].ID)
+	suite.True(sessions[0].Current)
+	suite.False(sessions[1].Current)
+}
+
+func (suite *AuthTestSuite) TestAuth_ListSessions_InvalidToken() {
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(nil, domain_errors.ErrTokenExpired)
+
+	sessions, err := suite.authService.ListSessions(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidToken)
+	suite.Nil(sessions)
+	suite.mockCache.AssertNotCalled(suite.T(), "ListSessions", mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_RevokeSession_Success() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+
+	target := &models.Session{ID: uuid.New(), UserID: claims.UserID}
+	suite.mockCache.On("GetSession", target.ID).Return(target, nil)
+	suite.mockCache.On("RemoveSession", claims.UserID, target.ID).Return(nil)
+
+	err := suite.authService.RevokeSession(suite.ctx, "token", target.ID)
+
+	suite.NoError(err)
+	suite.mockCache.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_RevokeSession_OtherUser() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+
+	foreign := &models.Session{ID: uuid.New(), UserID: uuid.New()}
+	suite.mockCache.On("GetSession", foreign.ID).Return(foreign, nil)
+
+	err := suite.authService.RevokeSession(suite.ctx, "token", foreign.ID)
+
+	suite.ErrorIs(err, domain_errors.ErrSessionNotFound)
+	suite.mockCache.AssertNotCalled(suite.T(), "RemoveSession", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_RevokeAllSessions() {
+	claims := suite.validClaims()
+	suite.mockjwtService.On("ValidateToken", "token", "").Return(claims, nil)
+	suite.mockCache.On("IsTokenRevoked", "revoked:jti").Return(false, nil)
+	suite.expectActiveSession(claims)
+	suite.mockCache.On("RemoveUserSessions", claims.UserID, []uuid.UUID(nil)).Return(nil)
+
+	err := suite.authService.RevokeAllSessions(suite.ctx, "token")
+
+	suite.NoError(err)
+	suite.mockCache.AssertExpectations(suite.T())
+}
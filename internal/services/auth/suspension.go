@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const TopicUserSuspended = "user-suspended"
+
+// UserSuspendedEvent announces a suspension. SuspendedUntil is omitted when
+// the suspension lasts until it is lifted.
+type UserSuspendedEvent struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	Reason         string     `json:"reason"`
+	SuspendedAt    time.Time  `json:"suspended_at"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+}
+
+// SuspendUser suspends the account until the given time, or until it is
+// lifted when until is zero, and ends every session of the user. Tokens of
+// the ended sessions are rejected by ValidateToken right away, or once they
+// expire while the revocation store is unreachable and the fail-open policy
+// is configured.
+func (a *Auth) SuspendUser(ctx context.Context, userID uuid.UUID, reason string, until time.Time) error {
+	if err := a.userSaver.SuspendUser(ctx, userID, reason, until); err != nil {
+		return fmt.Errorf("failed to suspend user: %w", err)
+	}
+
+	if err := a.redis.RemoveUserSessions(userID); err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+
+	event := &UserSuspendedEvent{
+		UserID:      userID,
+		Reason:      reason,
+		SuspendedAt: time.Now(),
+	}
+	if !until.IsZero() {
+		event.SuspendedUntil = &until
+	}
+
+	a.publish(TopicUserSuspended, userID.String(), event)
+
+	return nil
+}
+
+// UnsuspendUser lifts the suspension of the account
+func (a *Auth) UnsuspendUser(ctx context.Context, userID uuid.UUID) error {
+	if err := a.userSaver.UnsuspendUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to unsuspend user: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AuthTestSuite) TestAuth_SuspendUser() {
+	until := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Second)
+	suite.mockUserSaver.On("SuspendUser", suite.ctx, suite.expectedUser.ID, "spam", until).Return(nil)
+	suite.mockCache.On("RemoveUserSessions", suite.expectedUser.ID, []uuid.UUID(nil)).Return(nil)
+	produced := suite.expectProduce()
+
+	err := suite.authService.SuspendUser(suite.ctx, suite.expectedUser.ID, "spam", until)
+
+	suite.NoError(err)
+	suite.mockCache.AssertExpectations(suite.T())
+
+	var event UserSuspendedEvent
+	msg := <-produced
+	suite.Equal(TopicUserSuspended, msg.Topic)
+	suite.NoError(json.Unmarshal(msg.Value, &event))
+	suite.Equal(suite.expectedUser.ID, event.UserID)
+	suite.Equal("spam", event.Reason)
+	suite.True(until.Equal(*event.SuspendedUntil))
+}
+
+func (suite *AuthTestSuite) TestAuth_SuspendUser_UnknownUser() {
+	suite.mockUserSaver.On("SuspendUser", suite.ctx, suite.expectedUser.ID, "spam", time.Time{}).Return(domain_errors.ErrUserNotFound)
+
+	err := suite.authService.SuspendUser(suite.ctx, suite.expectedUser.ID, "spam", time.Time{})
+
+	suite.ErrorIs(err, domain_errors.ErrUserNotFound)
+	suite.mockCache.AssertNotCalled(suite.T(), "RemoveUserSessions", mock.Anything, mock.Anything)
+	suite.mockKafka.AssertNotCalled(suite.T(), "Produce", mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_Suspended() {
+	suite.expectedUser.Status = models.UserStatusSuspended
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrAccountSuspended)
+	suite.Nil(tokens)
+	suite.mockCache.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_SuspensionExpired() {
+	suite.expectedUser.Status = models.UserStatusSuspended
+	suite.expectedUser.SuspendedUntil = time.Now().Add(-time.Minute)
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+}
+
+func (suite *AuthTestSuite) TestAuth_RefreshToken_Suspended() {
+	session := suite.storedSession()
+	key := "refresh:" + opaque.Hash("refresh")
+	suite.expectedUser.Status = models.UserStatusSuspended
+
+	suite.mockCache.On("GetRefreshToken", key).Return(&models.RefreshToken{
+		UserID:    suite.expectedUser.ID,
+		SessionID: session.ID,
+	}, nil)
+	suite.mockCache.On("GetSession", session.ID).Return(session, nil)
+	suite.mockCache.On("MarkRefreshTokenRotated", key).Return(true, nil)
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(suite.expectedUser, nil)
+
+	tokens, err := suite.authService.RefreshToken(suite.ctx, "refresh")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidRefreshToken)
+	suite.Nil(tokens)
+	suite.mockjwtService.AssertNotCalled(suite.T(), "NewToken", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// accountThrottleKey keys the failures of an account by its id, so that
+// guessing through its email and through its username fills one window
+func accountThrottleKey(userID uuid.UUID) string {
+	return "login_failures:account:" + userID.String()
+}
+
+// identifierThrottleKey keys the failures of an identifier no account has.
+// It is hashed, so that the cache does not hold the email addresses being
+// guessed.
+func identifierThrottleKey(identifier string) string {
+	return "login_failures:identifier:" + opaque.Hash(strings.ToLower(strings.TrimSpace(identifier)))
+}
+
+// loginThrottleKey returns the account window a login attempt counts against.
+// The user is nil when no account has the identifier.
+func loginThrottleKey(user *models.User, identifier string) string {
+	if user == nil {
+		return identifierThrottleKey(identifier)
+	}
+
+	return accountThrottleKey(user.ID)
+}
+
+func ipThrottleKey(ip string) string {
+	return "login_failures:ip:" + ip
+}
+
+// loginThrottles returns the windows a login attempt counts against. A limit
+// of zero turns its window off.
+func (a *Auth) loginThrottles(accountKey string, ip string) []models.LoginWindow {
+	var throttles []models.LoginWindow
+
+	if a.config.LoginMaxAccountFailures > 0 {
+		throttles = append(throttles, models.LoginWindow{Key: accountKey, MaxFailures: a.config.LoginMaxAccountFailures})
+	}
+
+	if a.config.LoginMaxIPFailures > 0 && ip != "" {
+		throttles = append(throttles, models.LoginWindow{Key: ipThrottleKey(ip), MaxFailures: a.config.LoginMaxIPFailures})
+	}
+
+	return throttles
+}
+
+// reserveLoginAttempt counts the attempt as failed before the password is
+// checked, so that concurrent guesses can not all pass the same count. It
+// rejects the attempt while any of the windows is backing off, telling when
+// the client may try again, and returns the id to release the attempt with.
+func (a *Auth) reserveLoginAttempt(throttles []models.LoginWindow) (string, error) {
+	if len(throttles) == 0 {
+		return "", nil
+	}
+
+	attempt := uuid.NewString()
+
+	retryAfter, err := a.redis.ReserveLoginAttempt(attempt, throttles, time.Now(), a.config.LoginThrottleWindow, models.LoginBackoff{
+		Base: a.config.LoginBackoffBase,
+		Max:  a.config.LoginBackoffMax,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not check login failures: %w", err)
+	}
+
+	if retryAfter > 0 {
+		return "", &domain_errors.LoginThrottledError{RetryAfter: retryAfter}
+	}
+
+	return attempt, nil
+}
+
+// releaseLoginAttempt takes back the attempt once the password turned out to
+// be right. The login goes on either way, so errors are only logged.
+func (a *Auth) releaseLoginAttempt(attempt string, throttles []models.LoginWindow) {
+	if attempt == "" {
+		return
+	}
+
+	keys := make([]string, 0, len(throttles))
+	for _, throttle := range throttles {
+		keys = append(keys, throttle.Key)
+	}
+
+	if err := a.redis.ReleaseLoginAttempt(attempt, keys); err != nil {
+		log.Printf("failed to release login attempt: %v", err)
+	}
+}
+
+// checkPasswordAttempt checks a password typed for the account, throttled
+// per account and per client address and counted towards the lockout. The
+// user is nil when no account has the identifier. Unknown and locked
+// accounts are compared against the dummy hash, so that their answer and
+// its timing are those of a wrong password. It reports whether the stored
+// hash should be upgraded.
+func (a *Auth) checkPasswordAttempt(user *models.User, identifier string, password string, client models.ClientInfo) (bool, error) {
+	// The email and the username of an account share its window
+	throttles := a.loginThrottles(loginThrottleKey(user, identifier), client.IP)
+
+	attempt, err := a.reserveLoginAttempt(throttles)
+	if err != nil {
+		return false, err
+	}
+
+	// A locked account takes no guesses, its owner learns about the lock by email
+	if user == nil || user.Locked() {
+		a.compareDummyPassword(password)
+		return false, domain_errors.ErrInvalidCredentials
+	}
+
+	needsRehash, err := a.verifyPassword(user, password)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrInvalidCredentials) {
+			a.countFailedLogin(user, client)
+		}
+		return false, err
+	}
+
+	a.releaseLoginAttempt(attempt, throttles)
+
+	return needsRehash, nil
+}
+
+// resetAccountThrottle clears the failures of the account after it logged in.
+// The window of the address keeps counting, as it may guess other accounts.
+func (a *Auth) resetAccountThrottle(userID uuid.UUID) {
+	if a.config.LoginMaxAccountFailures <= 0 {
+		return
+	}
+
+	if err := a.redis.ResetLoginFailures(accountThrottleKey(userID)); err != nil {
+		log.Printf("failed to reset login failures: %v", err)
+	}
+}
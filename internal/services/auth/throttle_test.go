@@ -0,0 +1,144 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/storage/redis"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/mock"
+)
+
+// enableLoginThrottling limits logins to 5 failures per account and 20 per
+// address within 15 minutes
+func (suite *AuthTestSuite) enableLoginThrottling() {
+	suite.config.LoginThrottleWindow = 15 * time.Minute
+	suite.config.LoginMaxAccountFailures = 5
+	suite.config.LoginMaxIPFailures = 20
+	suite.config.LoginBackoffBase = time.Second
+	suite.config.LoginBackoffMax = time.Minute
+}
+
+// expectReserve expects an attempt to be reserved against the windows of
+// the account key and, when given, of the address
+func (suite *AuthTestSuite) expectReserve(accountKey string, ip string, retryAfter time.Duration, err error) *mock.Call {
+	windows := []models.LoginWindow{{Key: accountKey, MaxFailures: 5}}
+	if ip != "" {
+		windows = append(windows, models.LoginWindow{Key: ipThrottleKey(ip), MaxFailures: 20})
+	}
+
+	return suite.mockCache.On(
+		"ReserveLoginAttempt",
+		mock.Anything,
+		windows,
+		mock.Anything,
+		15*time.Minute,
+		models.LoginBackoff{Base: time.Second, Max: time.Minute},
+	).Return(retryAfter, err)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_Throttled() {
+	suite.enableLoginThrottling()
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "10.0.0.1", 4*time.Second, nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", client)
+
+	var throttled *domain_errors.LoginThrottledError
+	suite.Require().ErrorAs(err, &throttled)
+	suite.ErrorIs(err, domain_errors.ErrTooManyLoginAttempts)
+	suite.Equal(4*time.Second, throttled.RetryAfter)
+	suite.Nil(tokens)
+	suite.mockCache.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+	suite.mockCache.AssertNotCalled(suite.T(), "ReleaseLoginAttempt", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_EmailAndUsernameShareWindow() {
+	suite.enableLoginThrottling()
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockUserProvider.On("GetUserByUsername", suite.ctx, "jdoe").Return(suite.expectedUser, nil)
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "", 0, nil).Twice()
+
+	_, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "wrong password", models.ClientInfo{})
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+
+	_, err = suite.authService.Login(suite.ctx, "jdoe", "wrong password", models.ClientInfo{})
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+
+	suite.mockCache.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_WrongPasswordKeepsAttempt() {
+	suite.enableLoginThrottling()
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	suite.mockUserProvider.On("GetUser", suite.ctx, "nobody@test.com").Return(nil, domain_errors.ErrUserNotFound)
+	suite.expectReserve(identifierThrottleKey(" Nobody@Test.com"), "10.0.0.1", 0, nil)
+
+	_, err := suite.authService.Login(suite.ctx, "nobody@test.com", "password", client)
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidCredentials)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.mockCache.AssertNotCalled(suite.T(), "ReleaseLoginAttempt", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_SuccessReleasesAttempt() {
+	suite.enableLoginThrottling()
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	var reserved string
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "10.0.0.1", 0, nil).Run(func(args mock.Arguments) {
+		reserved = args.String(0)
+	})
+	suite.mockCache.On("ReleaseLoginAttempt", mock.Anything, []string{
+		accountThrottleKey(suite.expectedUser.ID),
+		ipThrottleKey("10.0.0.1"),
+	}).Return(errors.New("connection refused"))
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+	suite.mockCache.On("ResetLoginFailures", accountThrottleKey(suite.expectedUser.ID)).Return(nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", client)
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+	suite.mockCache.AssertExpectations(suite.T())
+	suite.mockCache.AssertCalled(suite.T(), "ReleaseLoginAttempt", reserved, mock.Anything)
+	suite.mockCache.AssertNotCalled(suite.T(), "ResetLoginFailures", ipThrottleKey("10.0.0.1"))
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_ThrottleCheckFails() {
+	suite.enableLoginThrottling()
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.expectReserve(accountThrottleKey(suite.expectedUser.ID), "", 0, errors.New("connection refused"))
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.Error(err)
+	suite.NotErrorIs(err, domain_errors.ErrTooManyLoginAttempts)
+	suite.Nil(tokens)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_ConcurrentGuesses() {
+	suite.enableLoginThrottling()
+	server := miniredis.RunT(suite.T())
+	suite.config.RedisAddress = server.Addr()
+	hasher := &spyHasher{PasswordHasher: suite.authService.hasher}
+	suite.authService.hasher = hasher
+	suite.authService.redis = redis.NewRedis(suite.config)
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			_, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "wrong password", models.ClientInfo{})
+			suite.Error(err)
+		})
+	}
+	wg.Wait()
+
+	suite.Len(hasher.compared, suite.config.LoginMaxAccountFailures)
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"auth-service/internal/domain/models"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// GetUserByID returns the account with the given identifier
+func (a *Auth) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := a.userProvider.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// BatchGetUsers returns the accounts with the given identifiers. Unknown
+// identifiers are left out of the result.
+func (a *Auth) BatchGetUsers(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	users, err := a.userProvider.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+func (suite *AuthTestSuite) TestAuth_GetUserByID_NotFound() {
+	suite.mockUserProvider.On("GetUserByID", suite.ctx, suite.expectedUser.ID).Return(nil, domain_errors.ErrUserNotFound)
+
+	user, err := suite.authService.GetUserByID(suite.ctx, suite.expectedUser.ID)
+
+	suite.ErrorIs(err, domain_errors.ErrUserNotFound)
+	suite.Nil(user)
+}
+
+func (suite *AuthTestSuite) TestAuth_BatchGetUsers() {
+	ids := []uuid.UUID{suite.expectedUser.ID, uuid.New()}
+	suite.mockUserProvider.On("GetUsersByIDs", suite.ctx, ids).Return([]*models.User{suite.expectedUser}, nil)
+
+	users, err := suite.authService.BatchGetUsers(suite.ctx, ids)
+
+	suite.NoError(err)
+	suite.Equal([]*models.User{suite.expectedUser}, users)
+}
+
+func (suite *AuthTestSuite) TestAuth_BatchGetUsers_StorageError() {
+	ids := []uuid.UUID{suite.expectedUser.ID}
+	suite.mockUserProvider.On("GetUsersByIDs", suite.ctx, ids).Return(nil, errors.New("connection refused"))
+
+	users, err := suite.authService.BatchGetUsers(suite.ctx, ids)
+
+	suite.Error(err)
+	suite.Nil(users)
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/opaque"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const TopicEmailVerificationRequested = "user-email-verification-requested"
+
+// EmailVerificationRequestedEvent asks the mail service to send the token to the user
+type EmailVerificationRequestedEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func verificationTokenKey(token string) string {
+	return "verify_email:" + opaque.Hash(token)
+}
+
+// SendVerificationEmail issues a new verification token for the account with
+// this email. Unknown and already verified addresses are silently ignored, so
+// that the call does not tell which accounts exist.
+func (a *Auth) SendVerificationEmail(ctx context.Context, email string) error {
+	user, err := a.userProvider.GetUser(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.EmailVerified() {
+		return nil
+	}
+
+	return a.requestEmailVerification(user.ID, user.Email)
+}
+
+// VerifyEmail consumes a verification token and marks the address it was sent to as verified
+func (a *Auth) VerifyEmail(ctx context.Context, token string) error {
+	stored, err := a.redis.ConsumeOneTimeToken(verificationTokenKey(token))
+	if err != nil {
+		if errors.Is(err, domain_errors.ErrOneTimeTokenNotFound) {
+			return domain_errors.ErrInvalidVerificationToken
+		}
+		return fmt.Errorf("could not load verification token: %w", err)
+	}
+
+	if err = a.userSaver.SetEmailVerified(ctx, stored.UserID, stored.Email, time.Now()); err != nil {
+		// The account is gone or its address changed since the token was sent
+		if errors.Is(err, domain_errors.ErrUserNotFound) {
+			return domain_errors.ErrInvalidVerificationToken
+		}
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return nil
+}
+
+// requestEmailVerification stores a single-use verification token and asks
+// the mail service to deliver it
+func (a *Auth) requestEmailVerification(userID uuid.UUID, email string) error {
+	token, err := a.issueOneTimeToken(verificationTokenKey, userID, email, a.config.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	a.publish(TopicEmailVerificationRequested, userID.String(), &EmailVerificationRequestedEvent{
+		UserID:    userID,
+		Email:     email,
+		Token:     token,
+		ExpiresAt: time.Now().Add(a.config.VerificationTokenTTL),
+	})
+
+	return nil
+}
+
+// issueOneTimeToken generates a token and stores it under the key derived from it
+func (a *Auth) issueOneTimeToken(
+	key func(token string) string,
+	userID uuid.UUID,
+	email string,
+	ttl time.Duration,
+) (string, error) {
+	token, err := opaque.NewToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	err = a.redis.StoreOneTimeToken(key(token), &models.OneTimeToken{
+		UserID: userID,
+		Email:  email,
+	}, ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"encoding/json"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AuthTestSuite) TestAuth_SendVerificationEmail_Unverified() {
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockCache.On("StoreOneTimeToken", mock.AnythingOfType("string"), &models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, 24*time.Hour).Return(nil)
+	produced := suite.expectProduce()
+
+	err := suite.authService.SendVerificationEmail(suite.ctx, suite.expectedUser.Email)
+
+	suite.NoError(err)
+
+	var event EmailVerificationRequestedEvent
+	msg := <-produced
+	suite.Equal(TopicEmailVerificationRequested, msg.Topic)
+	suite.NoError(json.Unmarshal(msg.Value, &event))
+	suite.Equal(suite.expectedUser.Email, event.Email)
+	suite.NotEmpty(event.Token)
+
+	key := suite.mockCache.Calls[0].Arguments.String(0)
+	suite.Equal(verificationTokenKey(event.Token), key)
+}
+
+func (suite *AuthTestSuite) TestAuth_SendVerificationEmail_AlreadyVerified() {
+	suite.expectedUser.VerifiedAt = time.Now()
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	err := suite.authService.SendVerificationEmail(suite.ctx, suite.expectedUser.Email)
+
+	suite.NoError(err)
+	suite.mockCache.AssertNotCalled(suite.T(), "StoreOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_SendVerificationEmail_UnknownEmail() {
+	suite.mockUserProvider.On("GetUser", suite.ctx, "nobody@test.com").Return(nil, domain_errors.ErrUserNotFound)
+
+	err := suite.authService.SendVerificationEmail(suite.ctx, "nobody@test.com")
+
+	suite.NoError(err)
+	suite.mockCache.AssertNotCalled(suite.T(), "StoreOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_VerifyEmail_Success() {
+	suite.mockCache.On("ConsumeOneTimeToken", verificationTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  suite.expectedUser.Email,
+	}, nil)
+	suite.mockUserSaver.On("SetEmailVerified", suite.ctx, suite.expectedUser.ID, suite.expectedUser.Email, mock.Anything).Return(nil)
+
+	err := suite.authService.VerifyEmail(suite.ctx, "token")
+
+	suite.NoError(err)
+	suite.mockUserSaver.AssertExpectations(suite.T())
+}
+
+func (suite *AuthTestSuite) TestAuth_VerifyEmail_UnknownToken() {
+	suite.mockCache.On("ConsumeOneTimeToken", verificationTokenKey("token")).Return(nil, domain_errors.ErrOneTimeTokenNotFound)
+
+	err := suite.authService.VerifyEmail(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidVerificationToken)
+	suite.mockUserSaver.AssertNotCalled(suite.T(), "SetEmailVerified", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_VerifyEmail_AddressChanged() {
+	suite.mockCache.On("ConsumeOneTimeToken", verificationTokenKey("token")).Return(&models.OneTimeToken{
+		UserID: suite.expectedUser.ID,
+		Email:  "old@test.com",
+	}, nil)
+	suite.mockUserSaver.On("SetEmailVerified", suite.ctx, suite.expectedUser.ID, "old@test.com", mock.Anything).Return(domain_errors.ErrUserNotFound)
+
+	err := suite.authService.VerifyEmail(suite.ctx, "token")
+
+	suite.ErrorIs(err, domain_errors.ErrInvalidVerificationToken)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_UnverifiedBlocked() {
+	suite.config.BlockUnverifiedLogin = true
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.ErrorIs(err, domain_errors.ErrEmailNotVerified)
+	suite.Nil(tokens)
+	suite.mockCache.AssertNotCalled(suite.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (suite *AuthTestSuite) TestAuth_Login_VerifiedNotBlocked() {
+	suite.config.BlockUnverifiedLogin = true
+	suite.expectedUser.VerifiedAt = time.Now()
+	suite.mockUserProvider.On("GetUser", suite.ctx, suite.expectedUser.Email).Return(suite.expectedUser, nil)
+	suite.mockCache.On("CreateSession", mock.Anything, 24*time.Hour).Return(nil)
+	suite.expectTokensIssued()
+
+	tokens, err := suite.authService.Login(suite.ctx, suite.expectedUser.Email, "password", models.ClientInfo{})
+
+	suite.NoError(err)
+	suite.NotNil(tokens)
+}
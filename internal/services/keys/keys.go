@@ -0,0 +1,207 @@
+package keys
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/keywrap"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Keys manages the signing keyring shared by every replica through Postgres
+type Keys struct {
+	storage         KeyStorage
+	keyring         Keyring
+	wrapper         KeyWrapper
+	tokenTTL        time.Duration
+	refreshInterval time.Duration
+	stop            chan struct{}
+}
+
+type KeyStorage interface {
+	SaveSigningKey(ctx context.Context, key *models.SigningKey) error
+	ListSigningKeys(ctx context.Context, retiredAfter time.Time) ([]*models.SigningKey, error)
+	UpdateSigningKeyPEM(ctx context.Context, kid string, keyPEM []byte) error
+	PromoteSigningKey(ctx context.Context, kid string, activatesAt time.Time, retireOthersAt time.Time) error
+}
+
+// KeyWrapper encrypts private key material before it is stored, so that a
+// dump of the database is not enough to sign tokens. The key id is bound to
+// the ciphertext.
+type KeyWrapper interface {
+	Wrap(plaintext []byte, associated string) ([]byte, error)
+	Unwrap(wrapped []byte, associated string) ([]byte, error)
+}
+
+type Keyring interface {
+	SetKeys(keys []*jwt.SigningKey)
+}
+
+// New returns a new instance of the Keys service. Promotions take effect
+// after refreshInterval so that every replica knows the key before it is used,
+// and the previous keys keep verifying for tokenTTL after that. Without a
+// wrapper no keys can be added.
+func New(
+	storage KeyStorage,
+	keyring Keyring,
+	wrapper KeyWrapper,
+	tokenTTL time.Duration,
+	refreshInterval time.Duration,
+) *Keys {
+	return &Keys{
+		storage:         storage,
+		keyring:         keyring,
+		wrapper:         wrapper,
+		tokenTTL:        tokenTTL,
+		refreshInterval: refreshInterval,
+		stop:            make(chan struct{}),
+	}
+}
+
+// AddSigningKey generates a key and publishes it for verification without using it for signing
+func (k *Keys) AddSigningKey(ctx context.Context, algorithm string) (*models.SigningKey, error) {
+	if k.wrapper == nil {
+		return nil, domain_errors.ErrKeyEncryptionMissing
+	}
+
+	key, err := jwt.GenerateSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	plainPEM, err := jwt.EncodeSigningKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := k.wrapper.Wrap(plainPEM, key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt signing key: %w", err)
+	}
+
+	stored := &models.SigningKey{
+		ID:        key.ID,
+		Algorithm: key.Algorithm,
+		KeyPEM:    keyPEM,
+		CreatedAt: time.Now(),
+	}
+
+	if err = k.storage.SaveSigningKey(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	if err = k.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	stored.KeyPEM = nil
+	return stored, nil
+}
+
+// PromoteSigningKey makes the key the one new tokens are signed with
+func (k *Keys) PromoteSigningKey(ctx context.Context, kid string) error {
+	activatesAt := time.Now().Add(k.refreshInterval)
+
+	if err := k.storage.PromoteSigningKey(ctx, kid, activatesAt, activatesAt.Add(k.tokenTTL)); err != nil {
+		return fmt.Errorf("could not promote signing key: %w", err)
+	}
+
+	return k.Reload(ctx)
+}
+
+// ListSigningKeys returns the keys that still verify tokens, without their key material
+func (k *Keys) ListSigningKeys(ctx context.Context) ([]*models.SigningKey, error) {
+	keys, err := k.storage.ListSigningKeys(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		key.KeyPEM = nil
+	}
+
+	return keys, nil
+}
+
+// Reload loads the keyring from storage
+func (k *Keys) Reload(ctx context.Context) error {
+	stored, err := k.storage.ListSigningKeys(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*jwt.SigningKey, 0, len(stored))
+	for _, s := range stored {
+		keyPEM, err := k.keyMaterial(ctx, s)
+		if err != nil {
+			log.Printf("skipping signing key %s: %v", s.ID, err)
+			continue
+		}
+
+		key, err := jwt.ParseSigningKey(s.Algorithm, keyPEM)
+		if err != nil {
+			log.Printf("skipping signing key %s: %v", s.ID, err)
+			continue
+		}
+
+		key.ID = s.ID
+		key.ActivatesAt = s.ActivatesAt
+		key.RetiresAt = s.RetiresAt
+		keys = append(keys, key)
+	}
+
+	k.keyring.SetKeys(keys)
+
+	return nil
+}
+
+// keyMaterial decrypts the stored key. Keys stored in the clear before
+// encryption was introduced are encrypted in place once a wrapper is set.
+func (k *Keys) keyMaterial(ctx context.Context, stored *models.SigningKey) ([]byte, error) {
+	if keywrap.IsWrapped(stored.KeyPEM) {
+		if k.wrapper == nil {
+			return nil, domain_errors.ErrKeyEncryptionMissing
+		}
+
+		return k.wrapper.Unwrap(stored.KeyPEM, stored.ID)
+	}
+
+	if k.wrapper != nil {
+		wrapped, err := k.wrapper.Wrap(stored.KeyPEM, stored.ID)
+		if err == nil {
+			err = k.storage.UpdateSigningKeyPEM(ctx, stored.ID, wrapped)
+		}
+		if err != nil {
+			log.Printf("failed to encrypt stored signing key %s: %v", stored.ID, err)
+		}
+	}
+
+	return stored.KeyPEM, nil
+}
+
+// StartRefresh periodically reloads the keyring so that keys added or
+// promoted on another replica are picked up
+func (k *Keys) StartRefresh() {
+	go func() {
+		ticker := time.NewTicker(k.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := k.Reload(context.Background()); err != nil {
+					log.Printf("failed to reload signing keys: %v", err)
+				}
+			case <-k.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (k *Keys) Stop() {
+	close(k.stop)
+}
@@ -10,11 +10,11 @@ type Producer struct {
 	writer *kafka.Writer
 }
 
+// New returns a producer for the broker. Every message names its own topic.
 func New(broker string) *Producer {
 	return &Producer{
 		writer: &kafka.Writer{
 			Addr:         kafka.TCP(broker),
-			Topic:        "user-created",
 			Balancer:     &kafka.LeastBytes{},
 			RequiredAcks: kafka.RequireOne,
 			Async:        false,
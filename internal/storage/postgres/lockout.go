@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecordFailedLogin counts a failed login of the user, deleted users
+// included as restoring an account takes their password. Failures before
+// windowStart no longer count. The failure that brings the count to
+// maxFailures locks the account until lockedUntil and starts the count over.
+// It reports whether this failure locked the account.
+func (s *Storage) RecordFailedLogin(
+	ctx context.Context,
+	id uuid.UUID,
+	failedAt time.Time,
+	windowStart time.Time,
+	maxFailures int,
+	lockedUntil time.Time,
+) (bool, error) {
+	var locked bool
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`WITH counted AS (
+			SELECT id, CASE WHEN last_failed_login_at > $3 THEN failed_login_count + 1 ELSE 1 END AS failures
+			FROM users WHERE id = $1
+			FOR UPDATE
+		)
+		UPDATE users SET
+			failed_login_count = CASE WHEN counted.failures >= $4 THEN 0 ELSE counted.failures END,
+			last_failed_login_at = $2,
+			locked_until = CASE WHEN counted.failures >= $4 THEN $5 ELSE users.locked_until END
+		FROM counted WHERE users.id = counted.id
+		RETURNING counted.failures >= $4`,
+		id,
+		failedAt,
+		windowStart,
+		maxFailures,
+		lockedUntil,
+	).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, domain_errors.ErrUserNotFound
+		}
+		return false, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	return locked, nil
+}
+
+// ResetFailedLogins forgets the failed logins of the user after a successful one
+func (s *Storage) ResetFailedLogins(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET failed_login_count=0, last_failed_login_at=NULL WHERE id=$1 AND failed_login_count > 0`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockUser lifts the lock of the account and forgets its failed logins
+func (s *Storage) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET locked_until=NULL, failed_login_count=0, last_failed_login_at=NULL
+		WHERE id=$1 AND deleted_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+
+	return expectUpdated(res)
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"auth-service/internal/domain/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GetPasswordHistory loads the hashes of at most limit passwords the user
+// had before the current one, retired after the given time, newest first
+func (s *Storage) GetPasswordHistory(
+	ctx context.Context,
+	userID uuid.UUID,
+	limit int,
+	retiredAfter time.Time,
+) ([]models.PasswordHash, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT password_hash, pepper_version FROM password_history
+		WHERE user_id = $1 AND created_at > $3
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		userID,
+		limit,
+		retiredAfter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password history: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []models.PasswordHash
+	for rows.Next() {
+		var hash models.PasswordHash
+		if err = rows.Scan(&hash.Hash, &hash.PepperVersion); err != nil {
+			return nil, fmt.Errorf("failed to scan password history: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// SetPassword replaces the password hash of the user and moves the previous
+// one into the history. Only the newest historySize entries retired after
+// the given time are kept.
+func (s *Storage) SetPassword(
+	ctx context.Context,
+	id uuid.UUID,
+	passHash []byte,
+	pepperVersion int,
+	historySize int,
+	retiredAfter time.Time,
+) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO password_history (user_id, password_hash, pepper_version)
+		SELECT id, password_hash, pepper_version FROM users WHERE id = $1 AND $2 > 0
+		FOR UPDATE`,
+		id,
+		historySize,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET password_hash = $2, pepper_version = $3 WHERE id = $1`,
+		id,
+		string(passHash),
+		pepperVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err = expectUpdated(res); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM password_history
+		WHERE user_id = $1 AND (created_at <= $3 OR id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+		))`,
+		id,
+		historySize,
+		retiredAfter,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune password history: %w", err)
+	}
+
+	return tx.Commit()
+}
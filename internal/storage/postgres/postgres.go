@@ -8,9 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -33,10 +33,12 @@ func New(storagePath string) (*Storage, error) {
 func (s *Storage) SaveUser(
 	ctx context.Context,
 	email string,
+	username string,
 	passHash []byte,
+	pepperVersion int,
 ) (uuid.UUID, error) {
 	var insertID uuid.UUID
-	stmt, err := s.db.Prepare(`INSERT INTO users (id, email, password_hash) VALUES ($1, $2, $3) RETURNING id`)
+	stmt, err := s.db.Prepare(`INSERT INTO users (id, email, username, password_hash, pepper_version) VALUES ($1, $2, $3, $4, $5) RETURNING id`)
 
 	if err != nil {
 		return uuid.Nil, err
@@ -46,17 +48,10 @@ func (s *Storage) SaveUser(
 
 	id := uuid.New()
 
-	res, err := stmt.QueryContext(ctx, id, email, string(passHash))
+	res, err := stmt.QueryContext(ctx, id, email, username, string(passHash), pepperVersion)
 	if err != nil {
-		var pgxErr *pgconn.PgError
-
-		if errors.As(err, &pgxErr) && pgxErr.Code == "23505" {
-			switch pgxErr.ConstraintName {
-			case "users_email_key":
-				return uuid.Nil, domain_errors.ErrUserEmailExists
-			default:
-				return uuid.Nil, fmt.Errorf("unknown unique constraint error: %w", err)
-			}
+		if uniqueErr := uniqueViolation(err); uniqueErr != nil {
+			return uuid.Nil, uniqueErr
 		}
 
 		return uuid.Nil, fmt.Errorf("failed to insert user: %w", err)
@@ -72,25 +67,372 @@ func (s *Storage) SaveUser(
 	return insertID, err
 }
 
-// GetUser loads user auth data from DB
-func (s *Storage) GetUser(ctx context.Context, email string) (*models.User, error) {
-	stmt, err := s.db.PrepareContext(ctx, `SELECT id, email, password_hash FROM users WHERE email=$1`)
+// uniqueViolation maps a violated unique constraint of the users table to
+// its domain error. It returns nil for other errors.
+func uniqueViolation(err error) error {
+	var pgxErr *pgconn.PgError
 
-	if err != nil {
-		return nil, err
+	if !errors.As(err, &pgxErr) || pgxErr.Code != "23505" {
+		return nil
 	}
-	defer stmt.Close()
 
+	switch pgxErr.ConstraintName {
+	case "users_email_key":
+		return domain_errors.ErrUserEmailExists
+	case "users_username_key":
+		return domain_errors.ErrUserUsernameExists
+	default:
+		return fmt.Errorf("unknown unique constraint error: %w", err)
+	}
+}
+
+// userColumns are the columns scanUser expects, in order
+const userColumns = `id, email, COALESCE(username, ''), password_hash, pepper_version, verified_at, COALESCE(pending_email, ''), deleted_at,
+	status, COALESCE(suspension_reason, ''), suspended_until, created_at, failed_login_count, locked_until`
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (*models.User, error) {
 	var user models.User
-	err = stmt.QueryRowContext(ctx, email).Scan(
+	var verifiedAt, deletedAt, suspendedUntil, lockedUntil sql.NullTime
+
+	err := row.Scan(
 		&user.ID,
 		&user.Email,
-		&user.PassHash)
+		&user.Username,
+		&user.PassHash,
+		&user.PepperVersion,
+		&verifiedAt,
+		&user.PendingEmail,
+		&deletedAt,
+		&user.Status,
+		&user.SuspensionReason,
+		&suspendedUntil,
+		&user.CreatedAt,
+		&user.FailedLogins,
+		&lockedUntil)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain_errors.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+
+	user.VerifiedAt = verifiedAt.Time
+	user.DeletedAt = deletedAt.Time
+	user.SuspendedUntil = suspendedUntil.Time
+	user.LockedUntil = lockedUntil.Time
+
 	return &user, nil
 }
+
+// GetUser loads user auth data from DB. Deleted users are not returned by
+// this and the other lookups.
+func (s *Storage) GetUser(ctx context.Context, email string) (*models.User, error) {
+	stmt, err := s.db.PrepareContext(ctx, `SELECT `+userColumns+` FROM users WHERE email=$1 AND deleted_at IS NULL`)
+
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, email))
+}
+
+// GetUserByID loads user auth data from DB by its identifier
+func (s *Storage) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	stmt, err := s.db.PrepareContext(ctx, `SELECT `+userColumns+` FROM users WHERE id=$1 AND deleted_at IS NULL`)
+
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, id))
+}
+
+// GetUserByUsername loads user auth data from DB, matching the username regardless of case
+func (s *Storage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	stmt, err := s.db.PrepareContext(ctx, `SELECT `+userColumns+` FROM users WHERE lower(username)=lower($1) AND deleted_at IS NULL`)
+
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, username))
+}
+
+// SetEmailVerified records when the user confirmed the email address. It
+// fails with ErrUserNotFound when the user no longer has that address.
+func (s *Storage) SetEmailVerified(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET verified_at=$3 WHERE id=$1 AND email=$2`, id, email, verifiedAt)
+	if err != nil {
+		return fmt.Errorf("failed to verify user email: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// UpdatePassword replaces the password hash of the user
+func (s *Storage) UpdatePassword(ctx context.Context, id uuid.UUID, passHash []byte, pepperVersion int) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET password_hash=$2, pepper_version=$3 WHERE id=$1`,
+		id,
+		string(passHash),
+		pepperVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// SetPendingEmail records the address the user wants to move to until it is confirmed
+func (s *Storage) SetPendingEmail(ctx context.Context, id uuid.UUID, email string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET pending_email=$2 WHERE id=$1`, id, email)
+	if err != nil {
+		return fmt.Errorf("failed to set pending email: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// ConfirmEmailChange swaps the pending address in as the verified email of
+// the user and returns the previous one. It fails with ErrUserNotFound when
+// the address is no longer pending.
+func (s *Storage) ConfirmEmailChange(
+	ctx context.Context,
+	id uuid.UUID,
+	email string,
+	verifiedAt time.Time,
+) (string, error) {
+	var previousEmail string
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`WITH previous AS (SELECT email FROM users WHERE id=$1 FOR UPDATE)
+		UPDATE users SET email=$2, pending_email=NULL, verified_at=$3
+		WHERE id=$1 AND pending_email=$2
+		RETURNING (SELECT email FROM previous)`,
+		id,
+		email,
+		verifiedAt,
+	).Scan(&previousEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain_errors.ErrUserNotFound
+		}
+		if uniqueErr := uniqueViolation(err); uniqueErr != nil {
+			return "", uniqueErr
+		}
+		return "", fmt.Errorf("failed to change email: %w", err)
+	}
+
+	return previousEmail, nil
+}
+
+// RestoreEmail moves the user back to an address they proved to own and
+// drops any pending change
+func (s *Storage) RestoreEmail(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET email=$2, pending_email=NULL, verified_at=$3 WHERE id=$1`,
+		id,
+		email,
+		verifiedAt,
+	)
+	if err != nil {
+		if uniqueErr := uniqueViolation(err); uniqueErr != nil {
+			return uniqueErr
+		}
+		return fmt.Errorf("failed to restore email: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// GetUsersByIDs loads the users with the given identifiers. Unknown and
+// deleted users are left out of the result.
+func (s *Storage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL`,
+		values,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*models.User, 0, len(ids))
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetDeletedUser loads a deleted user that has not been purged yet by its
+// email or username
+func (s *Storage) GetDeletedUser(ctx context.Context, identifier string) (*models.User, error) {
+	stmt, err := s.db.PrepareContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users
+		WHERE (email=$1 OR lower(username)=lower($1)) AND deleted_at IS NOT NULL`,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, identifier))
+}
+
+// DeleteUser marks the user as deleted. The row is kept until PurgeDeletedUsers removes it.
+func (s *Storage) DeleteUser(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET deleted_at=$2 WHERE id=$1 AND deleted_at IS NULL`, id, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// RestoreUser clears the deletion mark of a user that has not been purged yet
+func (s *Storage) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET deleted_at=NULL WHERE id=$1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// PurgeDeletedUsers removes at most limit users deleted before the given
+// time and returns how many it removed. The users are handed to announce
+// while their rows are locked, and are only removed once it succeeds, so that
+// a failed announcement keeps them for the next purge. Rows locked by a
+// concurrent purge are skipped.
+func (s *Storage) PurgeDeletedUsers(
+	ctx context.Context,
+	deletedBefore time.Time,
+	limit int,
+	announce func(users []*models.User) error,
+) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE deleted_at < $1 LIMIT $2 FOR UPDATE SKIP LOCKED`,
+		deletedBefore,
+		limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get purged users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	ids := make([]string, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return 0, err
+		}
+		users = append(users, user)
+		ids = append(ids, user.ID.String())
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get purged users: %w", err)
+	}
+
+	if len(users) == 0 {
+		return 0, nil
+	}
+
+	if err = announce(users); err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM users WHERE id = ANY($1::uuid[])`, ids); err != nil {
+		return 0, fmt.Errorf("failed to purge users: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to purge users: %w", err)
+	}
+
+	return len(users), nil
+}
+
+// SuspendUser marks the user as suspended. A zero until suspends the user
+// until UnsuspendUser is called.
+func (s *Storage) SuspendUser(ctx context.Context, id uuid.UUID, reason string, until time.Time) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET status=$2, suspension_reason=$3, suspended_until=$4 WHERE id=$1 AND deleted_at IS NULL`,
+		id,
+		models.UserStatusSuspended,
+		reason,
+		sql.NullTime{Time: until, Valid: !until.IsZero()},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to suspend user: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// UnsuspendUser lifts the suspension of the user
+func (s *Storage) UnsuspendUser(ctx context.Context, id uuid.UUID) error {
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET status=$2, suspension_reason=NULL, suspended_until=NULL WHERE id=$1 AND deleted_at IS NULL`,
+		id,
+		models.UserStatusActive,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unsuspend user: %w", err)
+	}
+
+	return expectUpdated(res)
+}
+
+// expectUpdated fails with ErrUserNotFound when the statement changed no row
+func expectUpdated(res sql.Result) error {
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if updated == 0 {
+		return domain_errors.ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SaveSigningKey stores a new, not yet activated key
+func (s *Storage) SaveSigningKey(ctx context.Context, key *models.SigningKey) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO signing_keys (kid, algorithm, key_pem) VALUES ($1, $2, $3)`,
+		key.ID,
+		key.Algorithm,
+		string(key.KeyPEM),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert signing key: %w", err)
+	}
+
+	return nil
+}
+
+// ListSigningKeys loads every key that has not been retired before the given time
+func (s *Storage) ListSigningKeys(ctx context.Context, retiredAfter time.Time) ([]*models.SigningKey, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT kid, algorithm, key_pem, created_at, activates_at, retires_at
+		FROM signing_keys
+		WHERE retires_at IS NULL OR retires_at > $1
+		ORDER BY created_at`,
+		retiredAfter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.SigningKey
+	for rows.Next() {
+		var key models.SigningKey
+		var keyPEM string
+		var activatesAt, retiresAt sql.NullTime
+
+		if err = rows.Scan(&key.ID, &key.Algorithm, &keyPEM, &key.CreatedAt, &activatesAt, &retiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+
+		key.KeyPEM = []byte(keyPEM)
+		key.ActivatesAt = activatesAt.Time
+		key.RetiresAt = retiresAt.Time
+		keys = append(keys, &key)
+	}
+
+	return keys, rows.Err()
+}
+
+// UpdateSigningKeyPEM replaces the stored key material of the key
+func (s *Storage) UpdateSigningKeyPEM(ctx context.Context, kid string, keyPEM []byte) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE signing_keys SET key_pem = $2 WHERE kid = $1`, kid, string(keyPEM))
+	if err != nil {
+		return fmt.Errorf("failed to update signing key: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return domain_errors.ErrSigningKeyNotFound
+	}
+
+	return nil
+}
+
+// PromoteSigningKey schedules the key to become active and retires every
+// previously activated key once the tokens signed with it have expired
+func (s *Storage) PromoteSigningKey(ctx context.Context, kid string, activatesAt time.Time, retireOthersAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE signing_keys SET activates_at = $2 WHERE kid = $1 AND (retires_at IS NULL OR retires_at > now())`,
+		kid,
+		activatesAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to activate signing key: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return domain_errors.ErrSigningKeyNotFound
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE signing_keys SET retires_at = $2
+		WHERE kid <> $1 AND activates_at IS NOT NULL AND (retires_at IS NULL OR retires_at > $2)`,
+		kid,
+		retireOthersAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retire signing keys: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"auth-service/internal/domain/models"
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// reserveLoginAttemptScript checks every window and, when none of them is
+// backing off, adds the attempt to all of them in one step, so that
+// concurrent attempts can not all pass on the same count. KEYS are the
+// windows. ARGV are the attempt id, the time, the window length, the backoff
+// base and max, all in milliseconds, followed by the limit of every window.
+// It returns the milliseconds to wait, 0 when the attempt was added.
+var reserveLoginAttemptScript = redis.NewScript(`
+local now = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local base = tonumber(ARGV[4])
+local maxBackoff = tonumber(ARGV[5])
+local retryAfter = 0
+
+for i, key in ipairs(KEYS) do
+	redis.call("ZREMRANGEBYSCORE", key, "-inf", "(" .. (now - period))
+
+	local limit = tonumber(ARGV[5 + i])
+	local failures = redis.call("ZCARD", key)
+	if failures >= limit then
+		local latest = redis.call("ZRANGE", key, -1, -1, "WITHSCORES")
+		local backoff = base
+		for _ = limit + 1, failures do
+			if backoff >= maxBackoff then
+				break
+			end
+			backoff = backoff * 2
+		end
+		backoff = math.min(backoff, maxBackoff)
+		retryAfter = math.max(retryAfter, tonumber(latest[2]) + backoff - now)
+	end
+end
+
+if retryAfter > 0 then
+	return retryAfter
+end
+
+for _, key in ipairs(KEYS) do
+	redis.call("ZADD", key, now, ARGV[1])
+	redis.call("PEXPIRE", key, period)
+end
+
+return 0
+`)
+
+// ReserveLoginAttempt counts the attempt as a failure in every window ahead
+// of checking the password, unless one of the windows is backing off. It
+// returns how long the client has to wait, zero when the attempt was counted.
+// Failures older than the period are dropped on the way.
+func (app *Redis) ReserveLoginAttempt(
+	id string,
+	windows []models.LoginWindow,
+	at time.Time,
+	period time.Duration,
+	backoff models.LoginBackoff,
+) (time.Duration, error) {
+	ctx := context.Background()
+
+	keys := make([]string, 0, len(windows))
+	args := []any{id, at.UnixMilli(), period.Milliseconds(), backoff.Base.Milliseconds(), backoff.Max.Milliseconds()}
+	for _, window := range windows {
+		keys = append(keys, window.Key)
+		args = append(args, window.MaxFailures)
+	}
+
+	retryAfter, err := reserveLoginAttemptScript.Run(ctx, app.redisClient, keys, args...).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(retryAfter) * time.Millisecond, nil
+}
+
+// ReleaseLoginAttempt takes back an attempt that turned out not to fail
+func (app *Redis) ReleaseLoginAttempt(id string, keys []string) error {
+	ctx := context.Background()
+
+	_, err := app.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.ZRem(ctx, key, id)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// ResetLoginFailures forgets every failed login recorded under the key
+func (app *Redis) ResetLoginFailures(key string) error {
+	ctx := context.Background()
+
+	return app.redisClient.Del(ctx, key).Err()
+}
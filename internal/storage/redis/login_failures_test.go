@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"auth-service/internal/domain/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testBackoff = models.LoginBackoff{Base: time.Second, Max: time.Minute}
+
+// reserveFailures fills the window with n failures at the time given
+func reserveFailures(t *testing.T, store *Redis, window models.LoginWindow, at time.Time, n int) {
+	for i := range n {
+		retryAfter, err := store.ReserveLoginAttempt(strconv.Itoa(i), []models.LoginWindow{window}, at, 15*time.Minute, testBackoff)
+		require.NoError(t, err)
+		require.Zero(t, retryAfter)
+	}
+}
+
+func TestRedis_ReserveLoginAttempt_Backoff(t *testing.T) {
+	store, server := newTestRedis(t)
+	window := models.LoginWindow{Key: "login_failures:account:1", MaxFailures: 5}
+	at := time.UnixMilli(1_700_000_000_000)
+	reserveFailures(t, store, window, at, 5)
+
+	retryAfter, err := store.ReserveLoginAttempt("6", []models.LoginWindow{window}, at, 15*time.Minute, testBackoff)
+	require.NoError(t, err)
+	require.Equal(t, time.Second, retryAfter)
+	require.Equal(t, 15*time.Minute, server.TTL(window.Key))
+
+	// The rejected attempt is not counted, the next one after the wait is
+	retryAfter, err = store.ReserveLoginAttempt("6", []models.LoginWindow{window}, at.Add(time.Second), 15*time.Minute, testBackoff)
+	require.NoError(t, err)
+	require.Zero(t, retryAfter)
+
+	// Every failure past the limit doubles the wait
+	retryAfter, err = store.ReserveLoginAttempt("7", []models.LoginWindow{window}, at.Add(time.Second), 15*time.Minute, testBackoff)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, retryAfter)
+}
+
+func TestRedis_ReserveLoginAttempt_MaxBackoff(t *testing.T) {
+	store, _ := newTestRedis(t)
+	window := models.LoginWindow{Key: "login_failures:ip:10.0.0.1", MaxFailures: 1000}
+	at := time.UnixMilli(1_700_000_000_000)
+	reserveFailures(t, store, window, at, 1000)
+	window.MaxFailures = 1
+
+	retryAfter, err := store.ReserveLoginAttempt("next", []models.LoginWindow{window}, at, 15*time.Minute, testBackoff)
+
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, retryAfter)
+}
+
+func TestRedis_ReserveLoginAttempt_WindowExpires(t *testing.T) {
+	store, _ := newTestRedis(t)
+	window := models.LoginWindow{Key: "login_failures:account:1", MaxFailures: 5}
+	at := time.UnixMilli(1_700_000_000_000)
+	reserveFailures(t, store, window, at, 5)
+
+	retryAfter, err := store.ReserveLoginAttempt("6", []models.LoginWindow{window}, at.Add(16*time.Minute), 15*time.Minute, testBackoff)
+
+	require.NoError(t, err)
+	require.Zero(t, retryAfter)
+}
+
+func TestRedis_ReserveLoginAttempt_AllWindowsOrNone(t *testing.T) {
+	store, server := newTestRedis(t)
+	account := models.LoginWindow{Key: "login_failures:account:1", MaxFailures: 5}
+	ip := models.LoginWindow{Key: "login_failures:ip:10.0.0.1", MaxFailures: 2}
+	at := time.UnixMilli(1_700_000_000_000)
+	reserveFailures(t, store, ip, at, 2)
+
+	retryAfter, err := store.ReserveLoginAttempt("next", []models.LoginWindow{account, ip}, at, 15*time.Minute, testBackoff)
+
+	require.NoError(t, err)
+	require.Equal(t, time.Second, retryAfter)
+	require.False(t, server.Exists(account.Key))
+}
+
+func TestRedis_ReleaseLoginAttempt(t *testing.T) {
+	store, _ := newTestRedis(t)
+	account := models.LoginWindow{Key: "login_failures:account:1", MaxFailures: 1}
+	ip := models.LoginWindow{Key: "login_failures:ip:10.0.0.1", MaxFailures: 1}
+	at := time.UnixMilli(1_700_000_000_000)
+	windows := []models.LoginWindow{account, ip}
+
+	_, err := store.ReserveLoginAttempt("right password", windows, at, 15*time.Minute, testBackoff)
+	require.NoError(t, err)
+	require.NoError(t, store.ReleaseLoginAttempt("right password", []string{account.Key, ip.Key}))
+
+	retryAfter, err := store.ReserveLoginAttempt("next", windows, at, 15*time.Minute, testBackoff)
+	require.NoError(t, err)
+	require.Zero(t, retryAfter)
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// StoreOneTimeToken stores a token that can be consumed once before it expires
+func (app *Redis) StoreOneTimeToken(key string, token *models.OneTimeToken, ttl time.Duration) error {
+	ctx := context.Background()
+
+	_, err := app.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "user_id", token.UserID.String(), "email", token.Email)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+
+	return err
+}
+
+// ConsumeOneTimeToken deletes the token and returns it. Of concurrent calls
+// only one gets the token.
+func (app *Redis) ConsumeOneTimeToken(key string) (*models.OneTimeToken, error) {
+	ctx := context.Background()
+
+	var values *redis.MapStringStringCmd
+	_, err := app.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.HGetAll(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOneTimeToken(values.Val())
+}
+
+// GetOneTimeToken returns the token without consuming it
+func (app *Redis) GetOneTimeToken(key string) (*models.OneTimeToken, error) {
+	ctx := context.Background()
+
+	values, err := app.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOneTimeToken(values)
+}
+
+func parseOneTimeToken(values map[string]string) (*models.OneTimeToken, error) {
+	if len(values) == 0 {
+		return nil, domain_errors.ErrOneTimeTokenNotFound
+	}
+
+	userID, err := uuid.Parse(values["user_id"])
+	if err != nil {
+		return nil, fmt.Errorf("corrupted one-time token user id: %w", err)
+	}
+
+	return &models.OneTimeToken{
+		UserID: userID,
+		Email:  values["email"],
+	}, nil
+}
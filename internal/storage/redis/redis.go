@@ -2,8 +2,10 @@ package redis
 
 import (
 	"auth-service/internal/config"
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
 	"context"
-	"log"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,31 +26,68 @@ func NewRedis(config *config.Config) *Redis {
 	}
 }
 
-func (app *Redis) StoreToken(key string, value uuid.UUID, ttl time.Duration) {
+func (app *Redis) StoreRefreshToken(key string, token *models.RefreshToken, ttl time.Duration) error {
 	ctx := context.Background()
 
-	if err := app.redisClient.Ping(ctx).Err(); err != nil {
-		log.Printf("redis ping failed: %v", err)
-		return
+	_, err := app.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "user_id", token.UserID.String(), "session_id", token.SessionID.String())
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+
+	return err
+}
+
+func (app *Redis) GetRefreshToken(key string) (*models.RefreshToken, error) {
+	ctx := context.Background()
+
+	values, err := app.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, domain_errors.ErrInvalidRefreshToken
+	}
+
+	userID, err := uuid.Parse(values["user_id"])
+	if err != nil {
+		return nil, fmt.Errorf("corrupted refresh token user id: %w", err)
 	}
 
-	log.Printf("Trying to add token %s to redis with value %d with duration %s: ", key, value, ttl)
-	err := app.redisClient.Set(ctx, key, value, ttl).Err()
+	sessionID, err := uuid.Parse(values["session_id"])
 	if err != nil {
-		log.Println("[redis] failed to store token")
+		return nil, fmt.Errorf("corrupted refresh token session id: %w", err)
 	}
+
+	return &models.RefreshToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		Rotated:   values["rotated_at"] != "",
+	}, nil
 }
 
-func (app *Redis) RemoveToken(key string) error {
+// MarkRefreshTokenRotated flags the token as used and reports whether this
+// call was the first one to do so
+func (app *Redis) MarkRefreshTokenRotated(key string) (bool, error) {
 	ctx := context.Background()
 
-	if err := app.redisClient.Ping(ctx).Err(); err != nil {
-		return err
-	}
+	return app.redisClient.HSetNX(ctx, key, "rotated_at", time.Now().Unix()).Result()
+}
+
+func (app *Redis) RevokeToken(key string, ttl time.Duration) error {
+	ctx := context.Background()
 
-	if err := app.redisClient.Del(ctx, key).Err(); err != nil {
-		return err
+	return app.redisClient.Set(ctx, key, 1, ttl).Err()
+}
+
+func (app *Redis) IsTokenRevoked(key string) (bool, error) {
+	ctx := context.Background()
+
+	count, err := app.redisClient.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return count > 0, nil
 }
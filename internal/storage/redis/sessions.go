@@ -0,0 +1,188 @@
+package redis
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func sessionKey(id uuid.UUID) string {
+	return "session:" + id.String()
+}
+
+func userSessionsKey(userID uuid.UUID) string {
+	return "user_sessions:" + userID.String()
+}
+
+// CreateSession stores the session and indexes it under its user
+func (app *Redis) CreateSession(session *models.Session, ttl time.Duration) error {
+	ctx := context.Background()
+
+	_, err := app.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, sessionKey(session.ID),
+			"user_id", session.UserID.String(),
+			"created_at", session.CreatedAt.Unix(),
+			"last_seen_at", session.LastSeenAt.Unix(),
+			"client_ip", session.ClientIP,
+			"user_agent", session.UserAgent,
+			"audience", session.Audience,
+		)
+		pipe.Expire(ctx, sessionKey(session.ID), ttl)
+		pipe.SAdd(ctx, userSessionsKey(session.UserID), session.ID.String())
+		pipe.Expire(ctx, userSessionsKey(session.UserID), ttl)
+		return nil
+	})
+
+	return err
+}
+
+func (app *Redis) GetSession(id uuid.UUID) (*models.Session, error) {
+	ctx := context.Background()
+
+	values, err := app.redisClient.HGetAll(ctx, sessionKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSession(id, values)
+}
+
+// touchSessionScript updates a session only while it still exists, so that a
+// touch racing with a removal can not bring back a partial session without
+// a ttl. KEYS are the session and the user index, ARGV the last seen time
+// and the ttl in milliseconds, where 0 keeps the current ttl.
+var touchSessionScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], "user_id") == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1])
+if tonumber(ARGV[2]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	redis.call("PEXPIRE", KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// TouchSession records activity on the session and extends its lifetime.
+// A session removed in the meantime yields domain_errors.ErrSessionNotFound.
+func (app *Redis) TouchSession(session *models.Session, ttl time.Duration) error {
+	ctx := context.Background()
+
+	touched, err := touchSessionScript.Run(ctx, app.redisClient,
+		[]string{sessionKey(session.ID), userSessionsKey(session.UserID)},
+		session.LastSeenAt.Unix(), ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return err
+	}
+
+	if touched == 0 {
+		return domain_errors.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// ListSessions returns the live sessions of the user and forgets expired ones
+func (app *Redis) ListSessions(userID uuid.UUID) ([]*models.Session, error) {
+	ctx := context.Background()
+
+	ids, err := app.redisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*models.Session, 0, len(ids))
+	for _, rawID := range ids {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			continue
+		}
+
+		session, err := app.GetSession(id)
+		if err != nil {
+			if errors.Is(err, domain_errors.ErrSessionNotFound) {
+				app.redisClient.SRem(ctx, userSessionsKey(userID), rawID)
+				continue
+			}
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (app *Redis) RemoveSession(userID uuid.UUID, id uuid.UUID) error {
+	ctx := context.Background()
+
+	_, err := app.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(id))
+		pipe.SRem(ctx, userSessionsKey(userID), id.String())
+		return nil
+	})
+
+	return err
+}
+
+// RemoveUserSessions ends every session of the user except the given ones
+func (app *Redis) RemoveUserSessions(userID uuid.UUID, except ...uuid.UUID) error {
+	ctx := context.Background()
+
+	ids, err := app.redisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(except))
+	for _, id := range except {
+		keep[id.String()] = true
+	}
+
+	_, err = app.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			if keep[id] {
+				continue
+			}
+
+			pipe.Del(ctx, "session:"+id)
+			pipe.SRem(ctx, userSessionsKey(userID), id)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func parseSession(id uuid.UUID, values map[string]string) (*models.Session, error) {
+	// A hash without its owner is a leftover of a removed session
+	if values["user_id"] == "" {
+		return nil, domain_errors.ErrSessionNotFound
+	}
+
+	userID, err := uuid.Parse(values["user_id"])
+	if err != nil {
+		return nil, fmt.Errorf("corrupted session user id: %w", err)
+	}
+
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	lastSeenAt, _ := strconv.ParseInt(values["last_seen_at"], 10, 64)
+
+	return &models.Session{
+		ID:         id,
+		UserID:     userID,
+		CreatedAt:  time.Unix(createdAt, 0),
+		LastSeenAt: time.Unix(lastSeenAt, 0),
+		ClientIP:   values["client_ip"],
+		UserAgent:  values["user_agent"],
+		Audience:   values["audience"],
+	}, nil
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	domain_errors "auth-service/internal/domain/errors"
+	"auth-service/internal/domain/models"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+
+	return &Redis{redisClient: redis.NewClient(&redis.Options{Addr: server.Addr()})}, server
+}
+
+func newTestSession() *models.Session {
+	now := time.Now()
+
+	return &models.Session{
+		ID:         uuid.New(),
+		UserID:     uuid.New(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ClientIP:   "203.0.113.7",
+		UserAgent:  "test",
+	}
+}
+
+func TestRedis_TouchSession(t *testing.T) {
+	store, server := newTestRedis(t)
+	session := newTestSession()
+	require.NoError(t, store.CreateSession(session, time.Hour))
+
+	session.LastSeenAt = session.LastSeenAt.Add(time.Minute)
+	require.NoError(t, store.TouchSession(session, 2*time.Hour))
+
+	stored, err := store.GetSession(session.ID)
+	require.NoError(t, err)
+	require.Equal(t, session.LastSeenAt.Unix(), stored.LastSeenAt.Unix())
+	require.Equal(t, 2*time.Hour, server.TTL(sessionKey(session.ID)))
+	require.Equal(t, 2*time.Hour, server.TTL(userSessionsKey(session.UserID)))
+
+	// A ttl of 0 leaves the lifetime as it is
+	require.NoError(t, store.TouchSession(session, 0))
+	require.Equal(t, 2*time.Hour, server.TTL(sessionKey(session.ID)))
+}
+
+func TestRedis_TouchSession_AfterRemove(t *testing.T) {
+	store, server := newTestRedis(t)
+	session := newTestSession()
+	require.NoError(t, store.CreateSession(session, time.Hour))
+	require.NoError(t, store.RemoveSession(session.UserID, session.ID))
+
+	err := store.TouchSession(session, 0)
+
+	require.ErrorIs(t, err, domain_errors.ErrSessionNotFound)
+	require.False(t, server.Exists(sessionKey(session.ID)))
+
+	_, err = store.GetSession(session.ID)
+	require.ErrorIs(t, err, domain_errors.ErrSessionNotFound)
+}
+
+func TestRedis_GetSession_PartialHash(t *testing.T) {
+	store, server := newTestRedis(t)
+	id := uuid.New()
+	server.HSet(sessionKey(id), "last_seen_at", "1700000000")
+
+	_, err := store.GetSession(id)
+
+	require.ErrorIs(t, err, domain_errors.ErrSessionNotFound)
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            UUID PRIMARY KEY,
+    email         TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    CONSTRAINT users_email_key UNIQUE (email)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE signing_keys (
+    kid          TEXT PRIMARY KEY,
+    algorithm    TEXT        NOT NULL,
+    key_pem      TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    activates_at TIMESTAMPTZ,
+    retires_at   TIMESTAMPTZ
+);
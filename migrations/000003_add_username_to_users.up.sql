@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username TEXT;
+
+-- Usernames are unique regardless of case
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (lower(username));
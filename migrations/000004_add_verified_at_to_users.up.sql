@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at TIMESTAMPTZ;
+
+-- Accounts from before verification was introduced are trusted as they are
+UPDATE users SET verified_at = now() WHERE verified_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE password_history DROP COLUMN IF EXISTS pepper_version;
+ALTER TABLE users DROP COLUMN IF EXISTS pepper_version;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pepper_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE password_history ADD COLUMN IF NOT EXISTS pepper_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+package smappb
\ No newline at end of file
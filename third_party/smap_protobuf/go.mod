@@ -0,0 +1,3 @@
+module github.com/NormVR/smap_protobuf
+
+go 1.25.0